@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"math/big"
 
+	"github.com/0xbow-io/go-iden3-crypto/keccak256"
 	"github.com/0xbow-io/go-iden3-crypto/mimc7"
 	"github.com/0xbow-io/go-iden3-crypto/poseidon"
 )
@@ -25,28 +26,36 @@ func SHA256Hash(left Element, right Element) []byte {
 	return hash.Sum(nil)
 }
 
-func Poseidon(left Element, right Element) []byte {
-	result, err := poseidon.Hash([]*big.Int{
-		big.NewInt(0).SetBytes(left),
-		big.NewInt(0).SetBytes(right),
-	})
+// Keccak256 hashes the concatenation of left and right, each left-padded to 32 bytes like bytes32
+func Keccak256(left Element, right Element) []byte {
+	return keccak256.Hash(padBytes32(left), padBytes32(right))
+}
+
+func padBytes32(e Element) []byte {
+	if len(e) >= 32 {
+		return e
+	}
+	out := make([]byte, 32)
+	copy(out[32-len(e):], e)
+	return out
+}
 
+// Poseidon panics on inputs outside the field, use PoseidonHasher to get an error instead
+func Poseidon(left Element, right Element) []byte {
+	result, err := PoseidonHasher.Hash(left, right)
 	if err != nil {
 		panic(err.Error())
 	}
-	return result.Bytes()
+	return result
 }
 
+// Poseidon2 panics on inputs outside the field, use Poseidon2Hasher to get an error instead
 func Poseidon2(left Element, right Element) []byte {
-	result, err := poseidon.Poseidon2([]*big.Int{
-		big.NewInt(0).SetBytes(left),
-		big.NewInt(0).SetBytes(right),
-	})
-
+	result, err := Poseidon2Hasher.Hash(left, right)
 	if err != nil {
 		panic(err.Error())
 	}
-	return result.Bytes()
+	return result
 }
 
 func MIMC7(left Element, right Element) []byte {
@@ -56,3 +65,27 @@ func MIMC7(left Element, right Element) []byte {
 	)
 	return result.Bytes()
 }
+
+func PoseidonN(inputs []Element) []byte {
+	result, err := poseidon.Hash(elementsToBigInts(inputs))
+	if err != nil {
+		panic(err.Error())
+	}
+	return result.Bytes()
+}
+
+func Poseidon2N(inputs []Element) []byte {
+	result, err := poseidon.Poseidon2(elementsToBigInts(inputs))
+	if err != nil {
+		panic(err.Error())
+	}
+	return result.Bytes()
+}
+
+func elementsToBigInts(elements []Element) []*big.Int {
+	out := make([]*big.Int, len(elements))
+	for i, e := range elements {
+		out[i] = big.NewInt(0).SetBytes(e)
+	}
+	return out
+}
@@ -2,24 +2,61 @@ package fMerkleTree
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
+	"math/big"
 )
 
 type BaseTree struct {
 	levels      int
+	hasher      Hasher
+	hashName    string
 	hashFn      HashFunction
 	zeroElement Element
 	zeros       []Element
 	layers      [][]Element
+	history     *rootHistory
+	tombstones  map[int]bool
+	skipRemoved bool
+	sortedPairs bool
+	domain      *HashDomain
+	leaves      []Element
+	field       *big.Int
+	workers     int
+	width       int
+	storage     [][]byte
+	lengths     [][]uint8
+	deferred    *deferredHashes
+}
+
+func (bt BaseTree) Hasher() Hasher {
+	return bt.hasher
+}
+
+// setHasher sets the hasher and the HashFunction view of it used to convert the tree to the HashFunction based trees
+func (bt *BaseTree) setHasher(hasher Hasher) {
+	bt.hasher = hasher
+	bt.hashFn = HashFunc(hasher)
 }
 
 func (bt BaseTree) Capacity() int {
 	return int(math.Pow(2, float64(bt.levels)))
 }
 
-func (bt BaseTree) Layers() [][]Element {
-	return bt.layers
+// Layers commits pending deferred writes first, it returns nil if they cannot be hashed, see Commit
+func (bt *BaseTree) Layers() [][]Element {
+	if err := bt.Commit(); err != nil {
+		return nil
+	}
+	if bt.width == 0 {
+		return bt.layers
+	}
+	out := make([][]Element, len(bt.layers))
+	for level := range out {
+		out[level] = bt.detachAll(bt.layer(level))
+	}
+	return out
 }
 
 func (bt BaseTree) Zeros() []Element {
@@ -27,14 +64,23 @@ func (bt BaseTree) Zeros() []Element {
 }
 
 func (bt BaseTree) Elements() []Element {
-	return bt.layers[0]
+	return bt.detachAll(bt.leafValues())
 }
 
-func (bt BaseTree) Root() Element {
-	if len(bt.layers[bt.levels]) == 0 {
+// Root commits pending deferred writes first, it returns nil if they cannot be hashed, see CommitRoot
+func (bt *BaseTree) Root() Element {
+	if err := bt.Commit(); err != nil {
+		return nil
+	}
+	return bt.root()
+}
+
+// root returns the root as of the last commit
+func (bt BaseTree) root() Element {
+	if bt.layerLen(bt.levels) == 0 {
 		return bt.zeros[bt.levels]
 	}
-	return bt.layers[bt.levels][0]
+	return bt.detach(bt.at(bt.levels, 0))
 }
 
 /**
@@ -42,41 +88,65 @@ func (bt BaseTree) Root() Element {
 * @param element Element to insert
  */
 func (bt *BaseTree) Insert(element Element) error {
-	if len(bt.layers[0]) >= bt.Capacity() {
+	if bt.layerLen(0) >= bt.Capacity() {
 		return fmt.Errorf("tree is full")
 	}
-	return bt.Update(len(bt.layers[0]), element)
+	return bt.Update(bt.layerLen(0), element)
 }
 
-/*
-* Insert multiple elements into the tree.
-* @param {Array} elements Elements to insert
+/**
+* Insert multiple elements into the tree, see Append
+* With a root history the root after each element is recorded, as if they were inserted one by one.
+* Nothing is inserted and no root is recorded if any element is rejected
+* @param elements Elements to insert
  */
-
 func (bt *BaseTree) BulkInsert(elements []Element) error {
+	if bt.history == nil {
+		_, err := bt.Append(elements)
+		return err
+	}
+	from := bt.layerLen(0)
+	if from+len(elements) > bt.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	if err := bt.Commit(); err != nil {
+		return err
+	}
 	if len(elements) == 0 {
 		return nil
 	}
-	if len(bt.layers[0])+len(elements) > bt.Capacity() {
-		return fmt.Errorf("tree is full")
+	leaves, err := bt.appendedLeaves(from, elements)
+	if err != nil {
+		return err
 	}
-
-	for i := range elements {
-		bt.layers[0] = append(bt.layers[0], elements[i])
-		level := 0
-		index := len(bt.layers[0]) - 1
-		for index%2 == 1 {
-			level++
-			index >>= 1
-			left := bt.layers[level-1][index*2]
-			right := bt.layers[level-1][index*2+1]
-			bt.layers[level][index] = bt.hashFn(left, right)
-		}
+	levels, roots, err := bt.rehashAppend(leaves)
+	if err != nil {
+		return err
 	}
-	return bt.Insert(elements[len(elements)-1])
+	if bt.domain != nil {
+		bt.leaves = append(bt.leaves[:from:from], elements...)
+	}
+	if err := bt.commit(levels); err != nil {
+		return err
+	}
+	for i, root := range roots {
+		bt.history.push(RootHistoryEntry{Root: bt.detach(root), Size: from + i + 1})
+	}
+	return nil
 }
 
+// SetLayer sets node j of layer i, in fixed width mode it panics for a node longer than NodeWidth
 func (bt *BaseTree) SetLayer(i, j int, val Element) {
+	if err := bt.setLayer(i, j, val); err != nil {
+		panic(err)
+	}
+}
+
+// setLayer is SetLayer returning an error for a node longer than NodeWidth
+func (bt *BaseTree) setLayer(i, j int, val Element) error {
+	if bt.width > 0 {
+		return bt.setNode(i, j, val)
+	}
 	if len(bt.layers[i]) <= j {
 		tmp := make([]Element, j+1)
 		if bt.layers[i] != nil {
@@ -86,6 +156,7 @@ func (bt *BaseTree) SetLayer(i, j int, val Element) {
 		bt.layers[i] = tmp
 	}
 	bt.layers[i][j] = val
+	return nil
 }
 
 /**
@@ -94,11 +165,49 @@ func (bt *BaseTree) SetLayer(i, j int, val Element) {
 * @param element Updated element value
  */
 func (bt *BaseTree) Update(index int, element Element) error {
-	if index < 0 || index > len(bt.layers[0]) || index >= bt.Capacity() {
+	if index < 0 || index > bt.layerLen(0) || index >= bt.Capacity() {
 		return fmt.Errorf("index out of bounds: %d", index)
 	}
-	bt.SetLayer(0, index, element)
-	bt.processUpdate(index)
+	node, err := bt.leafNode(index, element)
+	if err != nil {
+		return err
+	}
+	if bt.deferred != nil {
+		if err := bt.setLeaf(index, element, node); err != nil {
+			return err
+		}
+		bt.deferred.mark(0, index)
+		return nil
+	}
+	parents, err := bt.processUpdate(index, node)
+	if err != nil {
+		return err
+	}
+	if err := bt.setLeaf(index, element, node); err != nil {
+		return err
+	}
+	for level, parent := range parents {
+		if err := bt.setLayer(level+1, index>>(level+1), parent); err != nil {
+			return err
+		}
+	}
+	bt.recordRoot()
+	return nil
+}
+
+// setLeaf stores element and its node at index
+func (bt *BaseTree) setLeaf(index int, element Element, node Element) error {
+	if bt.domain != nil {
+		if index == len(bt.leaves) {
+			bt.leaves = append(bt.leaves[:index:index], element)
+		} else {
+			bt.leaves[index] = element
+		}
+	}
+	if err := bt.setLayer(0, index, node); err != nil {
+		return err
+	}
+	delete(bt.tombstones, index)
 	return nil
 }
 
@@ -108,10 +217,13 @@ func (bt *BaseTree) Update(index int, element Element) error {
 * @returns {{pathElements: Object[], pathIndex: number[]}} An object containing adjacent elements and left-right index
  */
 func (bt *BaseTree) Path(index int) (ProofPath, error) {
-	if index < 0 || index >= len(bt.layers[0]) {
+	if index < 0 || index >= bt.layerLen(0) {
 		return ProofPath{}, fmt.Errorf("index out of bounds: %d", index)
 
 	}
+	if err := bt.Commit(); err != nil {
+		return ProofPath{}, err
+	}
 
 	var (
 		elIndex                 = index
@@ -123,8 +235,8 @@ func (bt *BaseTree) Path(index int) (ProofPath, error) {
 	for level := 0; level < bt.levels; level++ {
 		pathIndices[level] = elIndex % 2
 		leafIndex := elIndex ^ 1
-		if leafIndex < len(bt.layers[level]) {
-			pathElements[level] = bt.layers[level][leafIndex]
+		if leafIndex < bt.layerLen(level) {
+			pathElements[level] = bt.detach(bt.at(level, leafIndex))
 			pathPositions[level] = leafIndex
 		} else {
 			pathElements[level] = bt.zeros[level]
@@ -136,7 +248,7 @@ func (bt *BaseTree) Path(index int) (ProofPath, error) {
 		PathElements:  pathElements,
 		PathIndices:   pathIndices,
 		PathPositions: pathPositions,
-		PathRoot:      bt.layers[bt.levels][0]}, nil
+		PathRoot:      bt.detach(bt.at(bt.levels, 0))}, nil
 }
 
 /*
@@ -145,7 +257,10 @@ VerifyProof
 *
 */
 func (bt *BaseTree) VerifyProof(elem Element, proof ProofPath) error {
-	index := IndexOfElement(bt.layers[0], elem, 0, nil)
+	if err := bt.Commit(); err != nil {
+		return err
+	}
+	index := IndexOfElement(bt.leafValues(), elem, 0, nil)
 
 	var (
 		elIndex = index
@@ -156,8 +271,8 @@ func (bt *BaseTree) VerifyProof(elem Element, proof ProofPath) error {
 			return fmt.Errorf("invalid proof")
 		}
 		leafIndex := elIndex ^ 1
-		if leafIndex < len(bt.layers[level]) {
-			if !bytes.Equal(proof.PathElements[level], bt.layers[level][leafIndex]) {
+		if leafIndex < bt.layerLen(level) {
+			if !bytes.Equal(proof.PathElements[level], bt.at(level, leafIndex)) {
 				return fmt.Errorf("invalid proof")
 			}
 			if proof.PathPositions[level] != leafIndex {
@@ -175,50 +290,91 @@ func (bt *BaseTree) VerifyProof(elem Element, proof ProofPath) error {
 	}
 	return nil
 }
-func (bt *BaseTree) buildZeros() {
-	bt.zeros = make([]Element, bt.levels+1)
-	bt.zeros[0] = bt.zeroElement
-	for i := 1; i <= bt.levels; i++ {
-		bt.zeros[i] = bt.hashFn(bt.zeros[i-1], bt.zeros[i-1])
+func (bt *BaseTree) buildZeros() error {
+	zeros := make([]Element, bt.levels+1)
+	zero := bt.zeroElement
+	if bt.domain != nil {
+		var err error
+		if zero, err = bt.domain.hashLeaf(bt.zeroElement); err != nil {
+			return err
+		}
 	}
+	for i := 0; i <= bt.levels; i++ {
+		var err error
+		if i > 0 {
+			if zero, err = bt.hasher.Hash(zeros[i-1], zeros[i-1]); err != nil {
+				return err
+			}
+		}
+		if err = bt.checkNode(i, -1, zero); err != nil {
+			return err
+		}
+		zeros[i] = zero
+	}
+	bt.zeros = zeros
+	return nil
+}
+
+// buildZeros returns the roots of empty subtrees for every level from 0 to levels
+func buildZeros(levels int, zeroElement Element, hashFn HashFunction) []Element {
+	zeros := make([]Element, levels+1)
+	zeros[0] = zeroElement
+	for i := 1; i <= levels; i++ {
+		zeros[i] = hashFn(zeros[i-1], zeros[i-1])
+	}
+	return zeros
 }
 
-func (bt *BaseTree) processNodes(nodes []Element, layerIndex int) []Element {
+func (bt *BaseTree) processNodes(ctx context.Context, nodes []Element, layerIndex int) ([]Element, error) {
 	length := len(nodes)
-	currentLength := int(math.Ceil(float64(length) / 2))
-	currentLayer := make([]Element, currentLength)
-	currentLength--
-	starFrom := length - ((length % 2) ^ 1)
-	j := 0
-	for i := starFrom; i >= 0; i -= 2 {
-		if nodes[i-1] == nil {
-			break
-		}
-		left := nodes[i-1]
-		var right Element
-		if i == starFrom && length%2 == 1 {
-			right = bt.zeros[layerIndex-1]
-		} else {
-			right = nodes[i]
+	currentLayer := make([]Element, (length+1)/2)
+	// parents left of a missing node stay unknown, partial trees only hold the nodes after their edge
+	start := len(currentLayer)
+	for start > 0 && nodes[(start-1)*2] != nil {
+		start--
+	}
+	pairs := make([]Element, 0, 2*(len(currentLayer)-start))
+	pairs = append(pairs, nodes[start*2:]...)
+	if length%2 == 1 {
+		pairs = append(pairs, bt.zeros[layerIndex-1])
+	}
+	hashes, err := bt.hashLayer(ctx, pairs)
+	if err != nil {
+		return nil, err
+	}
+	for i, hash := range hashes {
+		if err := bt.checkNode(layerIndex, start+i, hash); err != nil {
+			return nil, err
 		}
-		currentLayer[currentLength-j] = bt.hashFn(left, right)
-		j++
 	}
-	return currentLayer
+	copy(currentLayer[start:], hashes)
+	return currentLayer, nil
 }
 
-func (bt *BaseTree) processUpdate(index int) {
-	for level := 1; level <= bt.levels; level++ {
+// processUpdate hashes node at index up to the root and returns the new nodes of levels 1 to levels
+// The tree is not modified
+func (bt *BaseTree) processUpdate(index int, node Element) ([]Element, error) {
+	parents := make([]Element, bt.levels)
+	for level := 0; level < bt.levels; level++ {
+		sibling := bt.zeros[level]
+		if index^1 < bt.layerLen(level) {
+			sibling = bt.at(level, index^1)
+		}
+		left, right := node, sibling
+		if index%2 == 1 {
+			left, right = sibling, node
+		}
+		var err error
+		if node, err = bt.hasher.Hash(left, right); err != nil {
+			return nil, err
+		}
 		index >>= 1
-		left := bt.layers[level-1][index*2]
-		var right Element
-		if index*2+1 < len(bt.layers[level-1]) {
-			right = bt.layers[level-1][index*2+1]
-		} else {
-			right = bt.zeros[level-1]
+		if err = bt.checkNode(level+1, index, node); err != nil {
+			return nil, err
 		}
-		bt.SetLayer(level, index, bt.hashFn(left, right))
+		parents[level] = node
 	}
+	return parents, nil
 }
 
 /**
@@ -230,7 +386,11 @@ func (bt *BaseTree) processUpdate(index int) {
 * @returns {number} Index if element is found, otherwise -1
  */
 func IndexOfElement(elements []Element, element Element, fromIndex int, comparator ComparatorFunction) int {
-	for i, ele := range elements {
+	if fromIndex < 0 {
+		fromIndex = 0
+	}
+	for i := fromIndex; i < len(elements); i++ {
+		ele := elements[i]
 		if comparator != nil {
 			if comparator(element, ele) {
 				return i
@@ -0,0 +1,271 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AppendResult describes a batched append, leaves From to To-1 were inserted
+type AppendResult struct {
+	Root Element `json:"root"`
+	From int     `json:"from"`
+	To   int     `json:"to"`
+}
+
+// RootUpdate is the root before and after a batch update
+type RootUpdate struct {
+	OldRoot Element `json:"oldRoot"`
+	NewRoot Element `json:"newRoot"`
+}
+
+// dirtyNodes are the changed nodes of one level, sorted by index
+type dirtyNodes struct {
+	indices []int
+	nodes   []Element
+}
+
+/**
+* Append elements and hash every affected parent once
+* The tree ends up as after calling Insert for each element, only the final root is added to the root history
+* @param elements Elements to append
+ */
+func (bt *BaseTree) Append(elements []Element) (AppendResult, error) {
+	from := bt.layerLen(0)
+	if from+len(elements) > bt.Capacity() {
+		return AppendResult{}, fmt.Errorf("tree is full")
+	}
+	if err := bt.Commit(); err != nil {
+		return AppendResult{}, err
+	}
+	if len(elements) == 0 {
+		return AppendResult{Root: bt.root(), From: from, To: from}, nil
+	}
+	leaves, err := bt.appendedLeaves(from, elements)
+	if err != nil {
+		return AppendResult{}, err
+	}
+	levels, err := bt.rehash(leaves, from+len(elements))
+	if err != nil {
+		return AppendResult{}, err
+	}
+	if bt.domain != nil {
+		bt.leaves = append(bt.leaves[:from:from], elements...)
+	}
+	if err := bt.commit(levels); err != nil {
+		return AppendResult{}, err
+	}
+	bt.recordRoot()
+	return AppendResult{Root: bt.root(), From: from, To: from + len(elements)}, nil
+}
+
+/**
+* Change several leaves and hash every affected parent once
+* The whole batch is rejected and the tree left unchanged if any index or element is invalid
+* @param updates New element values by leaf index, new leaves are added with Append
+ */
+func (bt *BaseTree) BatchUpdate(updates map[int]Element) error {
+	_, err := bt.BatchUpdateRoots(updates)
+	return err
+}
+
+// BatchUpdateRoots is BatchUpdate returning the root before and after the batch
+func (bt *BaseTree) BatchUpdateRoots(updates map[int]Element) (RootUpdate, error) {
+	if err := bt.Commit(); err != nil {
+		return RootUpdate{}, err
+	}
+	oldRoot := bt.root()
+	if len(updates) == 0 {
+		return RootUpdate{OldRoot: oldRoot, NewRoot: oldRoot}, nil
+	}
+	leaves := dirtyNodes{indices: make([]int, 0, len(updates)), nodes: make([]Element, len(updates))}
+	for index := range updates {
+		if index < 0 || index >= bt.layerLen(0) {
+			return RootUpdate{}, fmt.Errorf("index out of bounds: %d", index)
+		}
+		leaves.indices = append(leaves.indices, index)
+	}
+	sort.Ints(leaves.indices)
+	for i, index := range leaves.indices {
+		node, err := bt.leafNode(index, updates[index])
+		if err != nil {
+			return RootUpdate{}, err
+		}
+		leaves.nodes[i] = node
+	}
+	levels, err := bt.rehash(leaves, bt.layerLen(0))
+	if err != nil {
+		return RootUpdate{}, err
+	}
+	if bt.domain != nil {
+		for _, index := range leaves.indices {
+			bt.leaves[index] = updates[index]
+		}
+	}
+	if err := bt.commit(levels); err != nil {
+		return RootUpdate{}, err
+	}
+	bt.recordRoot()
+	return RootUpdate{OldRoot: oldRoot, NewRoot: bt.root()}, nil
+}
+
+// appendedLeaves checks elements and returns the nodes stored for them from index from of layers[0]
+func (bt BaseTree) appendedLeaves(from int, elements []Element) (dirtyNodes, error) {
+	leaves := dirtyNodes{indices: make([]int, len(elements)), nodes: make([]Element, len(elements))}
+	for i, element := range elements {
+		node, err := bt.leafNode(from+i, element)
+		if err != nil {
+			return dirtyNodes{}, err
+		}
+		leaves.indices[i] = from + i
+		leaves.nodes[i] = node
+	}
+	return leaves, nil
+}
+
+// leafNode checks element and returns the node stored for it in layers[0]
+func (bt BaseTree) leafNode(index int, element Element) (Element, error) {
+	if err := bt.checkField(index, element); err != nil {
+		return nil, err
+	}
+	node := element
+	if bt.domain != nil {
+		var err error
+		if node, err = bt.domain.hashLeaf(element); err != nil {
+			return nil, err
+		}
+	}
+	return node, bt.checkNode(0, index, node)
+}
+
+/**
+* Hash the parents of the changed leaves once per level
+* The tree is not modified, the result holds the changed nodes of every level including the leaves
+* @param leaves Changed nodes of layers[0]
+* @param length Length of layers[0] after the change
+ */
+func (bt *BaseTree) rehash(leaves dirtyNodes, length int) ([]dirtyNodes, error) {
+	out := make([]dirtyNodes, bt.levels+1)
+	out[0] = leaves
+	for level := 0; level < bt.levels; level++ {
+		dirty := out[level]
+		parents := dirtyNodes{}
+		pairs := make([]Element, 0, 2*len(dirty.indices))
+		for i := 0; i < len(dirty.indices); i++ {
+			index := dirty.indices[i]
+			left, right := index&^1, index|1
+			var leftNode, rightNode Element
+			if index == left {
+				leftNode = dirty.nodes[i]
+				if i+1 < len(dirty.indices) && dirty.indices[i+1] == right {
+					i++
+					rightNode = dirty.nodes[i]
+				} else {
+					rightNode = bt.node(level, right, length)
+				}
+			} else {
+				leftNode = bt.node(level, left, length)
+				rightNode = dirty.nodes[i]
+			}
+			parents.indices = append(parents.indices, index>>1)
+			pairs = append(pairs, leftNode, rightNode)
+		}
+		hashes, err := hashPairs(bt.hasher, pairs)
+		if err != nil {
+			return nil, err
+		}
+		for j, hash := range hashes {
+			if err := bt.checkNode(level+1, parents.indices[j], hash); err != nil {
+				return nil, err
+			}
+		}
+		parents.nodes = hashes
+		out[level+1] = parents
+		length = (length + 1) / 2
+	}
+	return out, nil
+}
+
+/**
+* Hash the path of each appended leaf in turn, as Insert would
+* The tree is not modified, the result holds the changed nodes of every level and the root after each leaf
+* @param leaves Nodes appended to layers[0]
+ */
+func (bt *BaseTree) rehashAppend(leaves dirtyNodes) ([]dirtyNodes, []Element, error) {
+	out := make([]dirtyNodes, bt.levels+1)
+	out[0] = leaves
+	roots := make([]Element, len(leaves.indices))
+	for i, index := range leaves.indices {
+		node := leaves.nodes[i]
+		for level := 0; level < bt.levels; level++ {
+			// nodes right of an appended leaf are empty
+			left, right := node, bt.zeros[level]
+			if index%2 == 1 {
+				left, right = bt.appendedNode(out[level], level, index-1), node
+			}
+			hash, err := bt.hasher.Hash(left, right)
+			if err != nil {
+				return nil, nil, err
+			}
+			index >>= 1
+			if err := bt.checkNode(level+1, index, hash); err != nil {
+				return nil, nil, err
+			}
+			node = hash
+			parents := &out[level+1]
+			if n := len(parents.indices); n > 0 && parents.indices[n-1] == index {
+				parents.nodes[n-1] = node
+			} else {
+				parents.indices = append(parents.indices, index)
+				parents.nodes = append(parents.nodes, node)
+			}
+		}
+		roots[i] = node
+	}
+	return out, roots, nil
+}
+
+// appendedNode returns a node from the appended nodes of a level, or from the layer if it comes before them
+func (bt BaseTree) appendedNode(dirty dirtyNodes, level, index int) Element {
+	if len(dirty.indices) > 0 && index >= dirty.indices[0] {
+		return dirty.nodes[index-dirty.indices[0]]
+	}
+	return bt.at(level, index)
+}
+
+// node returns a stored node, or the zero of the level past the end of the layer
+func (bt BaseTree) node(level, index, length int) Element {
+	if index < bt.layerLen(level) && index < length {
+		return bt.at(level, index)
+	}
+	return bt.zeros[level]
+}
+
+// commit writes the nodes computed by rehash into the layers
+func (bt *BaseTree) commit(levels []dirtyNodes) error {
+	for level, dirty := range levels {
+		if err := bt.commitLevel(level, dirty); err != nil {
+			return err
+		}
+	}
+	for _, index := range levels[0].indices {
+		delete(bt.tombstones, index)
+	}
+	return nil
+}
+
+// commitLevel writes nodes into a layer, growing it once
+func (bt *BaseTree) commitLevel(level int, dirty dirtyNodes) error {
+	if len(dirty.indices) == 0 {
+		return nil
+	}
+	if last, length := dirty.indices[len(dirty.indices)-1], len(bt.layers[level]); last >= length && bt.width == 0 {
+		// the layer may share its array with the elements passed to NewMerkleTree, so it is copied
+		bt.layers[level] = append(bt.layers[level][:length:length], make([]Element, last+1-length)...)
+	}
+	for i, index := range dirty.indices {
+		if err := bt.setLayer(level, index, dirty.nodes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
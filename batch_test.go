@@ -0,0 +1,156 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingHasher counts the pairs it hashes
+type countingHasher struct {
+	Hasher
+	calls *int
+}
+
+func (h countingHasher) Hash(left Element, right Element) (Element, error) {
+	*h.calls++
+	return h.Hasher.Hash(left, right)
+}
+
+func Test_Append(t *testing.T) {
+	elements := newTestElements(0, 300)
+
+	t.Run("should match repeated inserts", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{
+			nil,
+			{WithFixedWidth()},
+			{WithHashDomain(RFC6962)},
+			{WithSortedPairs()},
+		} {
+			for _, split := range [][2]int{{0, 300}, {1, 2}, {5, 117}, {64, 128}, {255, 300}} {
+				tree, err := NewMerkleTree(9, elements[:split[0]], Element{0}, SHA256Hash, opts...)
+				require.NoError(t, err)
+				expected, err := NewMerkleTree(9, elements[:split[0]], Element{0}, SHA256Hash, opts...)
+				require.NoError(t, err)
+				for _, element := range elements[split[0]:split[1]] {
+					require.NoError(t, expected.Insert(element))
+				}
+
+				result, err := tree.Append(elements[split[0]:split[1]])
+				require.NoError(t, err)
+				require.Equal(t, AppendResult{Root: expected.Root(), From: split[0], To: split[1]}, result)
+				require.Equal(t, expected.Layers(), tree.Layers())
+				require.Equal(t, expected.Elements(), tree.Elements())
+			}
+		}
+	})
+
+	t.Run("should hash every parent once", func(t *testing.T) {
+		calls := 0
+		tree, err := NewMerkleTreeWithHasher(10, []Element{}, Element{0}, countingHasher{PoseidonHasher, &calls})
+		require.NoError(t, err)
+		calls = 0
+		_, err = tree.Append(newTestElements(0, 1000))
+		require.NoError(t, err)
+		require.Equal(t, 500+250+125+63+32+16+8+4+2+1, calls)
+
+		calls = 0
+		require.NoError(t, tree.BulkInsert(newTestElements(0, 3)))
+		require.Equal(t, 2+1+1+1+1+1+1+1+1+1, calls)
+	})
+
+	t.Run("should leave the tree unchanged on errors", func(t *testing.T) {
+		tree, err := NewMerkleTree(2, elements[:1], Element{0}, Poseidon, WithBN254Field())
+		require.NoError(t, err)
+		root := tree.Root()
+		_, err = tree.Append(elements[:4])
+		require.Error(t, err)
+		_, err = tree.Append([]Element{{1}, Element(BN254ScalarField.Bytes())})
+		require.Error(t, err)
+		require.Equal(t, root, tree.Root())
+		require.Len(t, tree.Elements(), 1)
+
+		result, err := tree.Append(nil)
+		require.NoError(t, err)
+		require.Equal(t, AppendResult{Root: root, From: 1, To: 1}, result)
+	})
+}
+
+func Test_BatchUpdate(t *testing.T) {
+	elements := newTestElements(0, 200)
+	updates := map[int]Element{0: {201}, 3: {202}, 4: {203}, 5: {204}, 64: {205}, 127: {206}, 199: {207}}
+
+	t.Run("should match repeated updates", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{nil, {WithFixedWidth()}, {WithHashDomain(RFC6962)}} {
+			tree, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash, opts...)
+			require.NoError(t, err)
+			expected, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash, opts...)
+			require.NoError(t, err)
+			require.NoError(t, expected.Remove(3))
+			require.NoError(t, tree.Remove(3))
+			oldRoot := tree.Root()
+			for index, element := range updates {
+				require.NoError(t, expected.Update(index, element))
+			}
+
+			roots, err := tree.BatchUpdateRoots(updates)
+			require.NoError(t, err)
+			require.Equal(t, RootUpdate{OldRoot: oldRoot, NewRoot: expected.Root()}, roots)
+			require.Equal(t, expected.Layers(), tree.Layers())
+			require.Equal(t, expected.Elements(), tree.Elements())
+			require.False(t, tree.IsRemoved(3))
+		}
+	})
+
+	t.Run("should hash shared parents once", func(t *testing.T) {
+		calls := 0
+		tree, err := NewMerkleTreeWithHasher(8, elements, Element{0}, countingHasher{SHA256Hasher, &calls})
+		require.NoError(t, err)
+		calls = 0
+		require.NoError(t, tree.BatchUpdate(map[int]Element{4: {1}, 5: {2}, 6: {3}, 7: {4}}))
+		require.Equal(t, 2+1+1+1+1+1+1+1, calls)
+	})
+
+	t.Run("should reject the whole batch", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, elements, Element{0}, Poseidon, WithBN254Field(), WithRootHistory(10))
+		require.NoError(t, err)
+		root := tree.Root()
+		require.Error(t, tree.BatchUpdate(map[int]Element{0: {1}, 200: {2}}))
+		require.Error(t, tree.BatchUpdate(map[int]Element{0: {1}, -1: {2}}))
+		require.Error(t, tree.BatchUpdate(map[int]Element{0: {1}, 1: Element(BN254ScalarField.Bytes())}))
+		require.Equal(t, root, tree.Root())
+		require.Equal(t, elements, tree.Elements())
+		require.Len(t, tree.RootHistory(), 1)
+
+		roots, err := tree.BatchUpdateRoots(nil)
+		require.NoError(t, err)
+		require.Equal(t, RootUpdate{OldRoot: root, NewRoot: root}, roots)
+	})
+
+	t.Run("should not update below the edge of a partial tree", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		edge, err := tree.getTreeEdge(100)
+		require.NoError(t, err)
+		partial, err := NewPartialMerkleTree(8, edge, elements[100:], Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Error(t, partial.BatchUpdate(map[int]Element{99: {1}, 150: {2}}))
+		require.NoError(t, partial.BatchUpdate(map[int]Element{100: {1}, 150: {2}}))
+		require.NoError(t, tree.BatchUpdate(map[int]Element{100: {1}, 150: {2}}))
+		require.Equal(t, tree.Root(), partial.Root())
+	})
+}
+
+func Test_AppendAliasing(t *testing.T) {
+	elements := make([]Element, 2, 10)
+	elements[0], elements[1] = Element{1}, Element{2}
+	backing := elements[:10]
+	for _, opts := range [][]TreeOption{nil, {WithHashDomain(RFC6962)}} {
+		tree, err := NewMerkleTree(4, elements, Element{0}, SHA256Hash, opts...)
+		require.NoError(t, err)
+		_, err = tree.Append([]Element{{3}, {4}})
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{5}))
+		require.Equal(t, make([]Element, 8), backing[2:])
+	}
+}
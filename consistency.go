@@ -0,0 +1,157 @@
+package fMerkleTree
+
+import (
+	"fmt"
+)
+
+/**
+* Proof that the tree at NewSize is an append-only extension of the tree at OldSize.
+* Both roots are recomputed along the path of leaf OldSize: Frontier holds the complete
+* subtrees left of that path, which both trees must share, Zeros the padding of the old
+* tree and Leaf plus Right the appended part of the new tree.
+ */
+type ConsistencyProof struct {
+	OldSize  int       `json:"oldSize"`
+	NewSize  int       `json:"newSize"`
+	Zeros    []Element `json:"zeros"`
+	Frontier []Element `json:"frontier"`
+	Leaf     Element   `json:"leaf"`
+	Right    []Element `json:"right"`
+}
+
+/**
+* Get a proof that the first oldSize leaves are unchanged in the tree of newSize leaves
+* Leaves below newSize must not have been updated since the tree held newSize leaves
+* @param oldSize Size of the earlier tree
+* @param newSize Size of the later tree, at most the current number of leaves
+ */
+func (mt *MerkleTree) ConsistencyProof(oldSize, newSize int) (ConsistencyProof, error) {
+	if oldSize < 0 || oldSize > newSize || newSize > mt.layerLen(0) {
+		return ConsistencyProof{}, fmt.Errorf("invalid sizes: %d, %d", oldSize, newSize)
+	}
+	if err := mt.Commit(); err != nil {
+		return ConsistencyProof{}, err
+	}
+	proof := ConsistencyProof{
+		OldSize: oldSize,
+		NewSize: newSize,
+		Zeros:   append([]Element(nil), mt.zeros[:mt.levels]...),
+	}
+	if oldSize == newSize {
+		return proof, nil
+	}
+	proof.Leaf = mt.detach(mt.at(0, oldSize))
+	for level := 0; level < mt.levels; level++ {
+		index := oldSize >> level
+		if index%2 == 1 {
+			node, err := mt.subtreeRoot(level, index-1, newSize)
+			if err != nil {
+				return ConsistencyProof{}, err
+			}
+			proof.Frontier = append(proof.Frontier, node)
+		} else {
+			node, err := mt.subtreeRoot(level, index+1, newSize)
+			if err != nil {
+				return ConsistencyProof{}, err
+			}
+			proof.Right = append(proof.Right, node)
+		}
+	}
+	return proof, nil
+}
+
+// subtreeRoot returns the node at (level, index) of the tree restricted to its first size leaves
+func (bt *BaseTree) subtreeRoot(level, index, size int) (Element, error) {
+	start := index << level
+	end := (index + 1) << level
+	switch {
+	case start >= size:
+		return bt.zeros[level], nil
+	case end <= size:
+		return bt.detach(bt.at(level, index)), nil
+	}
+	left, err := bt.subtreeRoot(level-1, index*2, size)
+	if err != nil {
+		return nil, err
+	}
+	right, err := bt.subtreeRoot(level-1, index*2+1, size)
+	if err != nil {
+		return nil, err
+	}
+	return bt.hasher.Hash(left, right)
+}
+
+/**
+* Verify that newRoot extends oldRoot by appending leaves only
+* A panic inside hashFn is returned as an error
+* @param oldRoot Trusted root of the tree at oldSize
+* @param newRoot Trusted root of the tree at newSize
+* @param proof Proof returned by MerkleTree.ConsistencyProof
+* @param hashFn Hash function the tree was built with
+ */
+func VerifyConsistency(oldRoot, newRoot Element, oldSize, newSize int, proof ConsistencyProof, hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	return VerifyConsistencyWithHasher(oldRoot, newRoot, oldSize, newSize, proof, hasherOf(hashFn))
+}
+
+// VerifyConsistencyWithHasher is VerifyConsistency returning the errors of hasher
+func VerifyConsistencyWithHasher(oldRoot, newRoot Element, oldSize, newSize int, proof ConsistencyProof, hasher Hasher) error {
+	if hasher == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	levels := len(proof.Zeros)
+	if proof.OldSize != oldSize || proof.NewSize != newSize {
+		return fmt.Errorf("invalid proof: sizes do not match")
+	}
+	if levels >= 63 || oldSize < 0 || oldSize > newSize || newSize > 1<<levels {
+		return fmt.Errorf("invalid sizes: %d, %d", oldSize, newSize)
+	}
+	if oldSize == newSize {
+		if len(proof.Frontier) != 0 || len(proof.Right) != 0 || !oldRoot.Cmp(newRoot) {
+			return fmt.Errorf("invalid proof: root mismatch")
+		}
+		return nil
+	}
+
+	oldNode := proof.Zeros[0]
+	newNode := proof.Leaf
+	frontier, right := proof.Frontier, proof.Right
+	var err error
+	for level := 0; level < levels; level++ {
+		if (oldSize>>level)%2 == 1 {
+			if len(frontier) == 0 {
+				return fmt.Errorf("invalid proof: not enough frontier nodes")
+			}
+			if oldNode, err = hasher.Hash(frontier[0], oldNode); err != nil {
+				return err
+			}
+			if newNode, err = hasher.Hash(frontier[0], newNode); err != nil {
+				return err
+			}
+			frontier = frontier[1:]
+		} else {
+			if len(right) == 0 {
+				return fmt.Errorf("invalid proof: not enough right nodes")
+			}
+			if oldNode, err = hasher.Hash(oldNode, proof.Zeros[level]); err != nil {
+				return err
+			}
+			if newNode, err = hasher.Hash(newNode, right[0]); err != nil {
+				return err
+			}
+			right = right[1:]
+		}
+	}
+	if len(frontier) != 0 || len(right) != 0 {
+		return fmt.Errorf("invalid proof: unused nodes")
+	}
+	if !oldNode.Cmp(oldRoot) {
+		return fmt.Errorf("invalid proof: old root mismatch")
+	}
+	if !newNode.Cmp(newRoot) {
+		return fmt.Errorf("invalid proof: new root mismatch")
+	}
+	return nil
+}
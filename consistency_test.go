@@ -0,0 +1,50 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ConsistencyProof(t *testing.T) {
+	const levels = 4
+	tree, err := NewMerkleTree(levels, []Element{}, Element{0}, SHA256Hash)
+	require.NoError(t, err)
+	roots := []Element{tree.Root()}
+	for i := 1; i <= 1<<levels; i++ {
+		require.NoError(t, tree.Insert(Element{byte(i)}))
+		roots = append(roots, tree.Root())
+	}
+
+	t.Run("should verify every pair of sizes", func(t *testing.T) {
+		for oldSize := 0; oldSize < len(roots); oldSize++ {
+			for newSize := oldSize; newSize < len(roots); newSize++ {
+				proof, err := tree.ConsistencyProof(oldSize, newSize)
+				require.NoError(t, err)
+				require.NoError(t, VerifyConsistency(roots[oldSize], roots[newSize], oldSize, newSize, proof, SHA256Hash), "%d -> %d", oldSize, newSize)
+			}
+		}
+	})
+
+	t.Run("should detect rewritten history", func(t *testing.T) {
+		rewritten, err := NewMerkleTree(levels, tree.Elements()[:9], Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, rewritten.Update(2, Element{42}))
+		proof, err := rewritten.ConsistencyProof(5, 9)
+		require.NoError(t, err)
+		require.Error(t, VerifyConsistency(roots[5], rewritten.Root(), 5, 9, proof, SHA256Hash))
+	})
+
+	t.Run("should reject mismatched sizes", func(t *testing.T) {
+		proof, err := tree.ConsistencyProof(3, 7)
+		require.NoError(t, err)
+		require.Error(t, VerifyConsistency(roots[3], roots[7], 3, 8, proof, SHA256Hash))
+		require.Error(t, VerifyConsistency(roots[3], roots[8], 3, 7, proof, SHA256Hash))
+		require.Error(t, VerifyConsistency(roots[3], roots[3], 3, 3, ConsistencyProof{OldSize: 3, NewSize: 3, Zeros: proof.Zeros, Right: proof.Right}, SHA256Hash))
+
+		_, err = tree.ConsistencyProof(7, 3)
+		require.Error(t, err)
+		_, err = tree.ConsistencyProof(0, 17)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,150 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// deferredHashes tracks per level the nodes that are current while their parents are not
+type deferredHashes struct {
+	dirty []map[int]bool
+}
+
+func (d *deferredHashes) mark(level, index int) {
+	if d.dirty[level] == nil {
+		d.dirty[level] = make(map[int]bool)
+	}
+	d.dirty[level][index] = true
+}
+
+func (d *deferredHashes) pending() bool {
+	for _, dirty := range d.dirty[:len(d.dirty)-1] {
+		if len(dirty) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+* Defer hashing until the nodes above the changed leaves are read
+* Insert, Update and Remove only store the leaf. Root, Layers, Path, IsKnownRoot and the proofs hash the dirty nodes
+* first, Node only hashes the dirty nodes of the subtree it reads. Only the final root of a commit is added to the
+* root history.
+ */
+func WithDeferredHashing() TreeOption {
+	return func(bt *BaseTree) error {
+		bt.deferred = &deferredHashes{dirty: make([]map[int]bool, bt.levels+1)}
+		return nil
+	}
+}
+
+func (bt BaseTree) DeferredHashing() bool {
+	return bt.deferred != nil
+}
+
+// Dirty reports whether there are writes that are not hashed up to the root yet
+func (bt BaseTree) Dirty() bool {
+	return bt.deferred != nil && bt.deferred.pending()
+}
+
+/**
+* Hash every dirty node up to the root
+* Does nothing without deferred hashing. On error the nodes that could not be hashed stay dirty
+ */
+func (bt *BaseTree) Commit() error {
+	if !bt.Dirty() {
+		return nil
+	}
+	if err := bt.flush(bt.levels, -1); err != nil {
+		return err
+	}
+	bt.recordRoot()
+	return nil
+}
+
+// CommitRoot hashes the pending writes and returns the root
+func (bt *BaseTree) CommitRoot() (Element, error) {
+	if err := bt.Commit(); err != nil {
+		return nil, err
+	}
+	return bt.root(), nil
+}
+
+/**
+* Get a node, hashing only the dirty nodes below it
+* @param level Level of the node, 0 for the leaves
+* @param index Index of the node in its level
+ */
+func (bt *BaseTree) Node(level, index int) (Element, error) {
+	if level < 0 || level > bt.levels {
+		return nil, fmt.Errorf("level out of bounds: %d", level)
+	}
+	if index < 0 || index >= bt.Capacity()>>level {
+		return nil, fmt.Errorf("index out of bounds: %d", index)
+	}
+	if level == bt.levels {
+		if err := bt.Commit(); err != nil {
+			return nil, err
+		}
+	} else if bt.Dirty() {
+		if err := bt.flush(level, index); err != nil {
+			return nil, err
+		}
+	}
+	if index < bt.layerLen(level) {
+		return bt.detach(bt.at(level, index)), nil
+	}
+	return bt.zeros[level], nil
+}
+
+// flush hashes the dirty nodes below level top, only those inside the subtree of (top, index) when index >= 0
+func (bt *BaseTree) flush(top, index int) error {
+	for level := 0; level < top; level++ {
+		dirty := bt.deferred.dirty[level]
+		indices := make([]int, 0, len(dirty))
+		for i := range dirty {
+			if index < 0 || i>>(top-level) == index {
+				indices = append(indices, i)
+			}
+		}
+		if len(indices) == 0 {
+			continue
+		}
+		sort.Ints(indices)
+		length := bt.layerLen(level)
+		parents := dirtyNodes{}
+		pairs := make([]Element, 0, 2*len(indices))
+		for _, i := range indices {
+			parent := i >> 1
+			if n := len(parents.indices); n > 0 && parents.indices[n-1] == parent {
+				continue
+			}
+			parents.indices = append(parents.indices, parent)
+			pairs = append(pairs, bt.at(level, parent*2), bt.node(level, parent*2+1, length))
+		}
+		hashes, err := hashPairs(bt.hasher, pairs)
+		if err != nil {
+			return err
+		}
+		for j, hash := range hashes {
+			if err := bt.checkNode(level+1, parents.indices[j], hash); err != nil {
+				return err
+			}
+		}
+		parents.nodes = hashes
+		if err := bt.commitLevel(level+1, parents); err != nil {
+			return err
+		}
+		for _, parent := range parents.indices {
+			bt.deferred.mark(level+1, parent)
+		}
+		for _, i := range indices {
+			delete(dirty, i)
+		}
+	}
+	if top == bt.levels {
+		bt.deferred.dirty[top] = nil
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeferredHashing(t *testing.T) {
+	elements := newTestElements(0, 16)
+
+	t.Run("should match eager hashing", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{nil, {WithFixedWidth()}, {WithHashDomain(RFC6962)}} {
+			calls := 0
+			tree, err := NewMerkleTreeWithHasher(8, elements, Element{0}, countingHasher{SHA256Hasher, &calls}, append(opts, WithDeferredHashing())...)
+			require.NoError(t, err)
+			require.True(t, tree.DeferredHashing())
+			eager, err := NewMerkleTreeWithHasher(8, elements, Element{0}, SHA256Hasher, opts...)
+			require.NoError(t, err)
+
+			calls = 0
+			for _, element := range newTestElements(100, 200) {
+				require.NoError(t, tree.Insert(element))
+				require.NoError(t, eager.Insert(element))
+			}
+			require.NoError(t, tree.Update(3, Element{1}))
+			require.NoError(t, eager.Update(3, Element{1}))
+			require.NoError(t, tree.Remove(50))
+			require.NoError(t, eager.Remove(50))
+			require.Equal(t, 0, calls)
+			require.True(t, tree.Dirty())
+
+			require.NoError(t, tree.Commit())
+			require.False(t, tree.Dirty())
+			require.Less(t, calls, 100+50+25+13+7+4+2+1+1)
+			require.Equal(t, eager.Root(), tree.Root())
+			require.Equal(t, eager.Layers(), tree.Layers())
+			require.True(t, tree.IsRemoved(50))
+		}
+	})
+
+	t.Run("should hash on reads", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash, WithDeferredHashing(), WithRootHistory(10))
+		require.NoError(t, err)
+		eager, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		for _, element := range newTestElements(20, 25) {
+			require.NoError(t, tree.Insert(element))
+			require.NoError(t, eager.Insert(element))
+		}
+		require.True(t, tree.Dirty())
+		require.Equal(t, eager.Root(), tree.Root())
+		require.False(t, tree.Dirty())
+		require.Len(t, tree.RootHistory(), 2)
+
+		require.NoError(t, tree.Insert(Element{26}))
+		require.NoError(t, eager.Insert(Element{26}))
+		require.Equal(t, eager.Layers(), tree.Layers())
+		require.NoError(t, tree.Insert(Element{27}))
+		require.NoError(t, eager.Insert(Element{27}))
+		require.Equal(t, eager.Root(), tree.LastRoot())
+		require.NoError(t, tree.Insert(Element{28}))
+		require.NoError(t, eager.Insert(Element{28}))
+		root, err := tree.CommitRoot()
+		require.NoError(t, err)
+		require.Equal(t, eager.Root(), root)
+
+		require.NoError(t, tree.Update(7, Element{9}))
+		require.NoError(t, eager.Update(7, Element{9}))
+		require.True(t, tree.IsKnownRoot(eager.Root()))
+		require.False(t, tree.Dirty())
+		require.NoError(t, tree.Update(7, Element{9}))
+		path, err := tree.Path(7)
+		require.NoError(t, err)
+		require.NoError(t, path.Verify(Element{9}, eager.Root(), SHA256Hash))
+
+		require.NoError(t, tree.Insert(Element{30}))
+		require.NoError(t, eager.Insert(Element{30}))
+		result, err := tree.Append([]Element{{31}, {32}})
+		require.NoError(t, err)
+		_, err = eager.Append([]Element{{31}, {32}})
+		require.NoError(t, err)
+		require.Equal(t, eager.Root(), result.Root)
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		require.Equal(t, eager.Root(), data.GetRoot())
+	})
+
+	t.Run("should not hash clean subtrees", func(t *testing.T) {
+		calls := 0
+		tree, err := NewMerkleTreeWithHasher(4, elements, Element{0}, countingHasher{SHA256Hasher, &calls}, WithDeferredHashing())
+		require.NoError(t, err)
+		eager, err := NewMerkleTree(4, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, tree.Update(15, Element{1}))
+		require.NoError(t, eager.Update(15, Element{1}))
+
+		calls = 0
+		node, err := tree.Node(2, 0)
+		require.NoError(t, err)
+		require.Equal(t, eager.Layers()[2][0], node)
+		require.Equal(t, 0, calls)
+
+		node, err = tree.Node(2, 3)
+		require.NoError(t, err)
+		require.Equal(t, eager.Layers()[2][3], node)
+		require.Equal(t, 2, calls)
+		require.True(t, tree.Dirty())
+
+		root, err := tree.CommitRoot()
+		require.NoError(t, err)
+		require.Equal(t, eager.Root(), root)
+		require.Equal(t, 4, calls)
+		_, err = tree.Node(5, 0)
+		require.Error(t, err)
+		_, err = tree.Node(2, 4)
+		require.Error(t, err)
+	})
+
+	t.Run("should keep failed writes dirty", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, elements[:4], Element{0}, failingHasher{SHA256Hasher, Element{9}}, WithDeferredHashing())
+		require.NoError(t, err)
+		root := tree.Root()
+		require.NoError(t, tree.Insert(Element{9}))
+		require.Error(t, tree.Commit())
+		_, err = tree.CommitRoot()
+		require.Error(t, err)
+		require.Nil(t, tree.Root())
+		require.Nil(t, tree.Layers())
+		require.Nil(t, tree.LastRoot())
+		require.False(t, tree.IsKnownRoot(root))
+		_, err = tree.Path(0)
+		require.Error(t, err)
+		require.True(t, tree.Dirty())
+
+		require.NoError(t, tree.Update(4, Element{5}))
+		eager, err := NewMerkleTree(4, append(newTestElements(0, 4), Element{5}), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		root, err = tree.CommitRoot()
+		require.NoError(t, err)
+		require.Equal(t, eager.Root(), root)
+	})
+}
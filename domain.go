@@ -0,0 +1,223 @@
+package fMerkleTree
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xbow-io/go-iden3-crypto/poseidon"
+)
+
+/**
+* Separate hashing of leaves and internal nodes.
+* With a domain, layers[0] holds LeafHash of every element while Elements, IndexOf and the
+* tree slices keep returning the elements themselves. Paths start at the hashed leaves.
+ */
+type HashDomain struct {
+	// Name is recorded in the serialized state
+	Name     string
+	LeafHash func(leaf Element) []byte
+	// NodeHash replaces the hash function of the tree, the tree's own hash function is used when nil
+	NodeHash HashFunction
+}
+
+// RFC6962 hashes leaves as SHA-256(0x00 || leaf) and nodes as SHA-256(0x01 || left || right)
+var RFC6962 = HashDomain{
+	Name: "rfc6962",
+	LeafHash: func(leaf Element) []byte {
+		hash := sha256.New()
+		hash.Write([]byte{0x00})
+		hash.Write(leaf)
+		return hash.Sum(nil)
+	},
+	NodeHash: func(left Element, right Element) []byte {
+		hash := sha256.New()
+		hash.Write([]byte{0x01})
+		hash.Write(left)
+		hash.Write(right)
+		return hash.Sum(nil)
+	},
+}
+
+/**
+* Domain that tags leaves with Poseidon(tag, leaf), internal nodes keep the tree's hash function
+* @param tag Domain tag, must be inside the field
+ */
+func PoseidonLeafDomain(tag *big.Int) HashDomain {
+	return HashDomain{
+		Name: poseidonLeafPrefix + tag.String(),
+		LeafHash: func(leaf Element) []byte {
+			result, err := poseidon.Hash([]*big.Int{tag, big.NewInt(0).SetBytes(leaf)})
+			if err != nil {
+				panic(err.Error())
+			}
+			return result.Bytes()
+		},
+	}
+}
+
+// poseidonLeafPrefix starts the names of the domains returned by PoseidonLeafDomain
+const poseidonLeafPrefix = "poseidon-leaf-"
+
+var domainRegistry = map[string]HashDomain{RFC6962.Name: RFC6962}
+
+/**
+* Register a hash domain so that trees built with it can be deserialized without passing it
+* RFC6962 and the domains of PoseidonLeafDomain are known without registering them
+ */
+func RegisterHashDomain(domain HashDomain) error {
+	if domain.LeafHash == nil || domain.Name == "" {
+		return fmt.Errorf("hash domain needs a name and a leaf hash")
+	}
+	if strings.HasPrefix(domain.Name, poseidonLeafPrefix) {
+		return fmt.Errorf("hash domain names starting with %s are reserved", poseidonLeafPrefix)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := domainRegistry[domain.Name]; ok {
+		return fmt.Errorf("hash domain %s is already registered", domain.Name)
+	}
+	domainRegistry[domain.Name] = domain
+	return nil
+}
+
+// LookupHashDomain returns the hash domain with the given name
+func LookupHashDomain(name string) (HashDomain, bool) {
+	if tag, ok := strings.CutPrefix(name, poseidonLeafPrefix); ok {
+		value, ok := new(big.Int).SetString(tag, 10)
+		if !ok || value.String() != tag {
+			return HashDomain{}, false
+		}
+		return PoseidonLeafDomain(value), true
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	domain, ok := domainRegistry[name]
+	return domain, ok
+}
+
+/**
+* Hash leaves and internal nodes in separate domains
+* Trees built with a registered domain, RFC6962 or a PoseidonLeafDomain are deserialized with it
+* without passing it again
+ */
+func WithHashDomain(domain HashDomain) TreeOption {
+	return func(bt *BaseTree) error {
+		if domain.LeafHash == nil || domain.Name == "" {
+			return fmt.Errorf("hash domain needs a name and a leaf hash")
+		}
+		if bt.domain != nil {
+			return fmt.Errorf("hash domain already set")
+		}
+		bt.domain = &domain
+		if domain.NodeHash != nil {
+			size := 0
+			if bt.hasher != nil {
+				size = bt.hasher.OutputSize()
+			}
+			hasher := NewHasher(domain.Name, size, domain.NodeHash)
+			if bt.sortedPairs {
+				hasher = SortedPairHasher(hasher)
+			}
+			bt.setHasher(hasher)
+		}
+		return nil
+	}
+}
+
+func (bt BaseTree) HashDomain() *HashDomain {
+	return bt.domain
+}
+
+// hashLeaf calls LeafHash and returns a panic inside it as an error
+func (d HashDomain) hashLeaf(leaf Element) (out Element, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", d.Name, r)
+		}
+	}()
+	return d.LeafHash(leaf), nil
+}
+
+// hashLeaves moves the elements in layers[0] to leaves and replaces them with their leaf hashes
+func (bt *BaseTree) hashLeaves() error {
+	if bt.domain == nil {
+		return nil
+	}
+	nodes := make([]Element, len(bt.layers[0]))
+	for i, leaf := range bt.layers[0] {
+		node, err := bt.domain.hashLeaf(leaf)
+		if err != nil {
+			return err
+		}
+		if err := bt.checkNode(0, i, node); err != nil {
+			return err
+		}
+		nodes[i] = node
+	}
+	bt.leaves = bt.layers[0]
+	bt.layers[0] = nodes
+	return nil
+}
+
+// leafValues returns the elements as inserted, before leaf hashing
+func (bt BaseTree) leafValues() []Element {
+	if bt.domain != nil {
+		return bt.leaves
+	}
+	return bt.layer(0)
+}
+
+// leafValue returns the element at index as inserted, before leaf hashing
+func (bt BaseTree) leafValue(index int) Element {
+	if bt.domain != nil {
+		return bt.leaves[index]
+	}
+	return bt.at(0, index)
+}
+
+/**
+* Verify a path of a tree built with this domain against a trusted root
+* @param leaf Element the path was generated for
+* @param hashFn Hash function of the tree, only used when the domain has no NodeHash
+ */
+func (d HashDomain) VerifyPath(leaf Element, proof ProofPath, root Element, hashFn HashFunction) error {
+	if d.LeafHash == nil {
+		return fmt.Errorf("hash domain has no leaf hash")
+	}
+	if d.NodeHash != nil {
+		hashFn = d.NodeHash
+	}
+	return proof.Verify(d.LeafHash(leaf), root, hashFn)
+}
+
+/**
+* Verify a multiproof of a tree built with this domain against a trusted root
+* @param leaves Elements in the order of LeafIndices, before leaf hashing
+* @param hashFn Hash function of the tree, only used when the domain has no NodeHash
+ */
+func (d HashDomain) VerifyMultiProof(leaves []Element, proof MultiProof, root Element, hashFn HashFunction) error {
+	if d.LeafHash == nil {
+		return fmt.Errorf("hash domain has no leaf hash")
+	}
+	if d.NodeHash != nil {
+		hashFn = d.NodeHash
+	}
+	nodes := make([]Element, len(leaves))
+	for i, leaf := range leaves {
+		nodes[i] = d.LeafHash(leaf)
+	}
+	return proof.Verify(nodes, root, hashFn)
+}
+
+/**
+* Verify a consistency proof of a tree built with this domain
+* @param hashFn Hash function of the tree, only used when the domain has no NodeHash
+ */
+func (d HashDomain) VerifyConsistency(oldRoot, newRoot Element, oldSize, newSize int, proof ConsistencyProof, hashFn HashFunction) error {
+	if d.NodeHash != nil {
+		hashFn = d.NodeHash
+	}
+	return VerifyConsistency(oldRoot, newRoot, oldSize, newSize, proof, hashFn)
+}
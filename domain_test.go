@@ -0,0 +1,160 @@
+package fMerkleTree
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HashDomain(t *testing.T) {
+	leaves := []Element{{1}, {2}, {3}}
+
+	t.Run("should match RFC 6962", func(t *testing.T) {
+		// MTH of the empty string, RFC 6962 section 2.1
+		require.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", Element(RFC6962.LeafHash(Element{})).Hex())
+
+		tree, err := NewMerkleTree(1, leaves[:2], Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		left := sha256.Sum256([]byte{0x00, 1})
+		right := sha256.Sum256([]byte{0x00, 2})
+		expected := sha256.Sum256(append(append([]byte{0x01}, left[:]...), right[:]...))
+		require.Equal(t, Element(expected[:]), tree.Root())
+		require.Equal(t, leaves[:2], tree.Elements())
+		require.Equal(t, Element(left[:]), tree.Layers()[0][0])
+	})
+
+	t.Run("should prove and update leaves", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{4}))
+		require.NoError(t, tree.Update(1, Element{5}))
+		require.Equal(t, []Element{{1}, {5}, {3}, {4}}, tree.Elements())
+
+		plain, err := NewMerkleTree(4, []Element{}, RFC6962.LeafHash(Element{0}), RFC6962.NodeHash)
+		require.NoError(t, err)
+		for _, leaf := range tree.Elements() {
+			require.NoError(t, plain.Insert(RFC6962.LeafHash(leaf)))
+		}
+		require.Equal(t, plain.Root(), tree.Root())
+
+		for i, leaf := range tree.Elements() {
+			require.Equal(t, i, tree.IndexOf(leaf))
+			path, err := tree.Proof(leaf)
+			require.NoError(t, err)
+			require.NoError(t, tree.VerifyProof(leaf, path))
+			require.NoError(t, RFC6962.VerifyPath(leaf, path, tree.Root(), SHA256Hash))
+			require.Error(t, path.Verify(leaf, tree.Root(), RFC6962.NodeHash))
+		}
+	})
+
+	t.Run("should reject internal nodes as leaves", func(t *testing.T) {
+		tree, err := NewMerkleTree(2, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		path, err := tree.Path(0)
+		require.NoError(t, err)
+		node := tree.Layers()[1][0]
+		short := ProofPath{PathElements: path.PathElements[1:], PathIndices: path.PathIndices[1:]}
+		require.NoError(t, short.Verify(node, tree.Root(), RFC6962.NodeHash))
+		require.Error(t, RFC6962.VerifyPath(node, short, tree.Root(), SHA256Hash))
+	})
+
+	t.Run("should tag leaves with poseidon", func(t *testing.T) {
+		domain := PoseidonLeafDomain(big.NewInt(1))
+		tree, err := NewMerkleTree(3, leaves, Element{0}, Poseidon, WithHashDomain(domain))
+		require.NoError(t, err)
+		untagged, err := NewMerkleTree(3, leaves, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.NotEqual(t, untagged.Root(), tree.Root())
+		path, err := tree.Path(2)
+		require.NoError(t, err)
+		require.NoError(t, domain.VerifyPath(Element{3}, path, tree.Root(), Poseidon))
+	})
+
+	t.Run("should verify multiproofs and consistency proofs", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		oldRoot := tree.Root()
+		proof, err := tree.MultiProof([]int{0, 2})
+		require.NoError(t, err)
+		require.NoError(t, RFC6962.VerifyMultiProof([]Element{{1}, {3}}, proof, oldRoot, SHA256Hash))
+		require.Error(t, RFC6962.VerifyMultiProof([]Element{{1}, {4}}, proof, oldRoot, SHA256Hash))
+		require.Error(t, VerifyMultiProof([]Element{{1}, {3}}, proof, oldRoot, SHA256Hash))
+
+		require.NoError(t, tree.BulkInsert([]Element{{4}, {5}}))
+		consistency, err := tree.ConsistencyProof(3, 5)
+		require.NoError(t, err)
+		require.NoError(t, RFC6962.VerifyConsistency(oldRoot, tree.Root(), 3, 5, consistency, SHA256Hash))
+		require.Error(t, VerifyConsistency(oldRoot, tree.Root(), 3, 5, consistency, SHA256Hash))
+	})
+
+	t.Run("should remove leaves", func(t *testing.T) {
+		tree, err := NewMerkleTree(2, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962), WithSkipRemoved())
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(1))
+		require.Equal(t, []Element{{1}, {0}, {3}}, tree.Elements())
+		require.Equal(t, []LiveElement{{0, Element{1}}, {2, Element{3}}}, tree.LiveElements())
+		empty, err := NewMerkleTree(2, []Element{{1}, {0}, {3}}, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		require.Equal(t, empty.Root(), tree.Root())
+	})
+
+	t.Run("should survive serialization", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(0))
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+
+		_, err = DeserializeMerkleTree(data, SHA256Hash, WithHashDomain(PoseidonLeafDomain(big.NewInt(1))))
+		require.Error(t, err)
+
+		expected, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithHashDomain(RFC6962))
+		require.NoError(t, err)
+		require.NoError(t, expected.Remove(0))
+		require.NoError(t, expected.Insert(Element{9}))
+		for _, opts := range [][]TreeOption{nil, {WithHashDomain(RFC6962)}} {
+			tree2, err := DeserializeMerkleTree(data, SHA256Hash, opts...)
+			require.NoError(t, err)
+			require.Equal(t, RFC6962.Name, tree2.HashDomain().Name)
+			require.Equal(t, tree.Elements(), tree2.Elements())
+			require.True(t, tree2.IsRemoved(0))
+			require.NoError(t, tree2.Insert(Element{9}))
+			require.Equal(t, expected.Root(), tree2.Root())
+		}
+
+		domain := PoseidonLeafDomain(big.NewInt(7))
+		tagged, err := NewMerkleTreeWithHasher(3, leaves, Element{0}, PoseidonHasher, WithHashDomain(domain))
+		require.NoError(t, err)
+		data, err = tagged.Serialize()
+		require.NoError(t, err)
+		tree3, err := DeserializeMerkleTree(data, nil)
+		require.NoError(t, err)
+		require.Equal(t, domain.Name, tree3.HashDomain().Name)
+		require.NoError(t, tree3.Insert(Element{4}))
+		require.NoError(t, tagged.Insert(Element{4}))
+		require.Equal(t, tagged.Root(), tree3.Root())
+
+		custom := HashDomain{Name: "test-unregistered", LeafHash: RFC6962.LeafHash}
+		customTree, err := NewMerkleTree(2, leaves, Element{0}, SHA256Hash, WithHashDomain(custom))
+		require.NoError(t, err)
+		data, err = customTree.Serialize()
+		require.NoError(t, err)
+		_, err = DeserializeMerkleTree(data, SHA256Hash)
+		require.Error(t, err)
+		_, err = DeserializeMerkleTree(data, SHA256Hash, WithHashDomain(custom))
+		require.NoError(t, err)
+		_, ok := LookupHashDomain("poseidon-leaf-07")
+		require.False(t, ok)
+		require.Error(t, RegisterHashDomain(RFC6962))
+		require.Error(t, RegisterHashDomain(HashDomain{Name: "poseidon-leaf-1", LeafHash: RFC6962.LeafHash}))
+
+		plain, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		data, err = plain.Serialize()
+		require.NoError(t, err)
+		_, err = DeserializeMerkleTree(data, SHA256Hash, WithHashDomain(RFC6962))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,97 @@
+package fMerkleTree
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/0xbow-io/go-iden3-crypto/constants"
+)
+
+// BN254ScalarField is the order of the scalar field of BN254, the field Poseidon and MiMC7 work in
+var BN254ScalarField = new(big.Int).Set(constants.Q)
+
+// FieldError is returned when an element or a hashed node is not a canonical field element
+type FieldError struct {
+	// Level of the node, 0 for leaves
+	Level int
+	// Index of the node in its level, -1 for the zero element and the empty subtree roots
+	Index   int
+	Element Element
+	Modulus *big.Int
+}
+
+func (e *FieldError) Error() string {
+	switch {
+	case e.Level > 0 && e.Index < 0:
+		return fmt.Sprintf("zero node %s at level %d is not in the field", e.Element.Hex(), e.Level)
+	case e.Level > 0:
+		return fmt.Sprintf("node %s at level %d index %d is not in the field", e.Element.Hex(), e.Level, e.Index)
+	case e.Index < 0:
+		return fmt.Sprintf("zero element %s is not in the field", e.Element.Hex())
+	}
+	return fmt.Sprintf("element %s at %d is not in the field", e.Element.Hex(), e.Index)
+}
+
+// InField reports whether element is smaller than modulus
+func InField(element Element, modulus *big.Int) bool {
+	return element.BigInt().Cmp(modulus) < 0
+}
+
+/**
+* Reject leaves, zero elements and hashed nodes that are not smaller than modulus with a *FieldError
+* A hash function that does not reduce its output fails on the first node outside the field,
+* combine it with ReduceModP, e.g. ReduceModP(SHA256Hash, modulus), to keep every node in the field
+* @param modulus Field modulus, e.g. BN254ScalarField
+ */
+func WithFieldModulus(modulus *big.Int) TreeOption {
+	return func(bt *BaseTree) error {
+		if modulus == nil || modulus.Sign() <= 0 {
+			return fmt.Errorf("field modulus must be positive")
+		}
+		bt.field = new(big.Int).Set(modulus)
+		return nil
+	}
+}
+
+// WithBN254Field is WithFieldModulus(BN254ScalarField)
+func WithBN254Field() TreeOption {
+	return WithFieldModulus(BN254ScalarField)
+}
+
+func (bt BaseTree) FieldModulus() *big.Int {
+	return bt.field
+}
+
+func (bt BaseTree) checkField(index int, element Element) error {
+	if bt.field != nil && !InField(element, bt.field) {
+		return &FieldError{Index: index, Element: element, Modulus: bt.field}
+	}
+	return nil
+}
+
+// checkNode rejects a node that is not in the field or does not fit in its slot
+func (bt BaseTree) checkNode(level, index int, node Element) error {
+	if bt.field != nil && !InField(node, bt.field) {
+		return &FieldError{Level: level, Index: index, Element: node, Modulus: bt.field}
+	}
+	return bt.checkWidth(node)
+}
+
+// ReduceModP wraps hashFn so that its output is reduced modulo modulus
+func ReduceModP(hashFn HashFunction, modulus *big.Int) HashFunction {
+	return func(left Element, right Element) []byte {
+		result := new(big.Int).SetBytes(hashFn(left, right))
+		return result.Mod(result, modulus).Bytes()
+	}
+}
+
+// SHA256ModP hashes the two 32-byte words with SHA-256 and reduces the result into the BN254 scalar field,
+// as uint256(sha256(abi.encodePacked(left, right))) % FIELD_SIZE does in Solidity
+func SHA256ModP(left Element, right Element) []byte {
+	hash := sha256.New()
+	hash.Write(padBytes32(left))
+	hash.Write(padBytes32(right))
+	result := new(big.Int).SetBytes(hash.Sum(nil))
+	return result.Mod(result, BN254ScalarField).Bytes()
+}
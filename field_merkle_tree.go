@@ -0,0 +1,231 @@
+package fMerkleTree
+
+import (
+	"fmt"
+
+	"github.com/0xbow-io/go-iden3-crypto/ff"
+)
+
+/**
+* Fixed depth Poseidon merkle tree whose nodes are stored as BN254 field elements in Montgomery form.
+* Hashing never goes through big.Int, Elements are converted only when they enter or leave the tree.
+* Roots, layers and paths are bit-for-bit those of a MerkleTree built with Poseidon: leaves are kept as they
+* were inserted, nodes above them are returned in the minimal big-endian encoding Poseidon produces.
+ */
+type FieldMerkleTree struct {
+	levels      int
+	zeroElement Element
+	zeros       []ff.Element
+	layers      [][]ff.Element
+	// leaves as they were inserted, layers[0] holds their field values
+	leaves []Element
+}
+
+/**
+* Build a Poseidon tree of field elements
+* Returns a *FieldError for a leaf or zero element that is not in the BN254 scalar field
+* @param levels Number of levels of the tree
+* @param elements Leaves of the tree
+* @param zeroElement Value of empty leaves
+ */
+func NewFieldMerkleTree(levels int, elements []Element, zeroElement Element) (*FieldMerkleTree, error) {
+	if levels < 0 || levels > 62 {
+		return nil, fmt.Errorf("invalid levels: %d", levels)
+	}
+	zero, err := toFieldElement(-1, zeroElement)
+	if err != nil {
+		return nil, err
+	}
+	out := &FieldMerkleTree{
+		levels:      levels,
+		zeroElement: zeroElement,
+		zeros:       make([]ff.Element, levels+1),
+		layers:      make([][]ff.Element, levels+1),
+	}
+	out.zeros[0] = zero
+	for level := 1; level <= levels; level++ {
+		out.zeros[level] = poseidonField(&out.zeros[level-1], &out.zeros[level-1])
+	}
+	if err := out.BulkInsert(elements); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (ft FieldMerkleTree) Capacity() int {
+	return 1 << ft.levels
+}
+
+func (ft FieldMerkleTree) Root() Element {
+	root := ft.RootField()
+	return fromFieldElement(&root)
+}
+
+// RootField returns the root without converting it
+func (ft FieldMerkleTree) RootField() ff.Element {
+	if len(ft.layers[ft.levels]) == 0 {
+		return ft.zeros[ft.levels]
+	}
+	return ft.layers[ft.levels][0]
+}
+
+// Layers converts every node, use Root and Path to read single nodes
+func (ft FieldMerkleTree) Layers() [][]Element {
+	out := make([][]Element, len(ft.layers))
+	out[0] = ft.Elements()
+	for level := 1; level < len(ft.layers); level++ {
+		out[level] = fromFieldElements(ft.layers[level])
+	}
+	return out
+}
+
+func (ft FieldMerkleTree) Zeros() []Element {
+	out := fromFieldElements(ft.zeros)
+	out[0] = ft.zeroElement
+	return out
+}
+
+func (ft FieldMerkleTree) Elements() []Element {
+	return append([]Element{}, ft.leaves...)
+}
+
+func (ft FieldMerkleTree) IndexOf(element Element) int {
+	return IndexOfElement(ft.leaves, element, 0, nil)
+}
+
+/**
+* Insert new element into the tree
+* @param element Element to insert
+ */
+func (ft *FieldMerkleTree) Insert(element Element) error {
+	return ft.BulkInsert([]Element{element})
+}
+
+/**
+* Insert multiple elements into the tree, hashing every affected parent once
+* Nothing is inserted if an element is not in the field
+* @param elements Elements to insert
+ */
+func (ft *FieldMerkleTree) BulkInsert(elements []Element) error {
+	from := len(ft.layers[0])
+	leaves := make([]ff.Element, len(elements))
+	for i, element := range elements {
+		leaf, err := toFieldElement(from+i, element)
+		if err != nil {
+			return err
+		}
+		leaves[i] = leaf
+	}
+	return ft.appendField(leaves, elements)
+}
+
+/**
+* Insert leaves that are already field elements in Montgomery form
+* Elements and Path return them in the minimal big-endian encoding
+* @param leaves Leaves to insert
+ */
+func (ft *FieldMerkleTree) AppendField(leaves []ff.Element) error {
+	return ft.appendField(leaves, fromFieldElements(leaves))
+}
+
+// appendField inserts leaves, elements are their encodings returned by Elements and Path
+func (ft *FieldMerkleTree) appendField(leaves []ff.Element, elements []Element) error {
+	from := len(ft.layers[0])
+	if from+len(leaves) > ft.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	if len(leaves) == 0 {
+		return nil
+	}
+	ft.layers[0] = append(ft.layers[0], leaves...)
+	ft.leaves = append(ft.leaves, elements...)
+	for level := 0; level < ft.levels; level++ {
+		layer := ft.layers[level]
+		parent := from >> 1
+		next := ft.layers[level+1][:parent]
+		for i := parent; i < (len(layer)+1)/2; i++ {
+			next = append(next, ft.hashChildren(level, i))
+		}
+		ft.layers[level+1] = next
+		from = parent
+	}
+	return nil
+}
+
+/**
+* Change an element in the tree
+* @param index Index of element to change
+* @param element Updated element value
+ */
+func (ft *FieldMerkleTree) Update(index int, element Element) error {
+	if index < 0 || index >= len(ft.layers[0]) {
+		return fmt.Errorf("index out of bounds: %d", index)
+	}
+	leaf, err := toFieldElement(index, element)
+	if err != nil {
+		return err
+	}
+	ft.layers[0][index] = leaf
+	ft.leaves[index] = element
+	for level := 0; level < ft.levels; level++ {
+		index >>= 1
+		ft.layers[level+1][index] = ft.hashChildren(level, index)
+	}
+	return nil
+}
+
+// hashChildren hashes the children of node index of level+1
+func (ft FieldMerkleTree) hashChildren(level, index int) ff.Element {
+	layer := ft.layers[level]
+	right := ft.zeros[level]
+	if index*2+1 < len(layer) {
+		right = layer[index*2+1]
+	}
+	return poseidonField(&layer[index*2], &right)
+}
+
+/**
+* Get merkle path to a leaf
+* @param index Leaf index to generate path for
+* @returns the same path as MerkleTree.Path of a Poseidon tree with the same leaves
+ */
+func (ft FieldMerkleTree) Path(index int) (ProofPath, error) {
+	if index < 0 || index >= len(ft.layers[0]) {
+		return ProofPath{}, fmt.Errorf("index out of bounds: %d", index)
+	}
+	path := ProofPath{
+		PathElements:  make([]Element, ft.levels),
+		PathIndices:   make([]int, ft.levels),
+		PathPositions: make([]int, ft.levels),
+		PathRoot:      ft.Root(),
+	}
+	for level := 0; level < ft.levels; level++ {
+		path.PathIndices[level] = index % 2
+		sibling := index ^ 1
+		if sibling < len(ft.leaves) && level == 0 {
+			path.PathElements[level] = ft.leaves[sibling]
+			path.PathPositions[level] = sibling
+		} else if sibling < len(ft.layers[level]) {
+			path.PathElements[level] = fromFieldElement(&ft.layers[level][sibling])
+			path.PathPositions[level] = sibling
+		} else if level == 0 {
+			path.PathElements[level] = ft.zeroElement
+		} else {
+			path.PathElements[level] = fromFieldElement(&ft.zeros[level])
+		}
+		index >>= 1
+	}
+	return path, nil
+}
+
+func (ft FieldMerkleTree) Proof(element Element) (ProofPath, error) {
+	return ft.Path(ft.IndexOf(element))
+}
+
+func fromFieldElements(elements []ff.Element) []Element {
+	out := make([]Element, len(elements))
+	for i := range elements {
+		out[i] = fromFieldElement(&elements[i])
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FieldMerkleTree(t *testing.T) {
+	elements := make([]Element, 0, 40)
+	for i := 1; i <= 40; i++ {
+		elements = append(elements, Poseidon(Element{byte(i)}, Element{0}))
+	}
+
+	t.Run("should match a Poseidon tree", func(t *testing.T) {
+		for _, levels := range []int{0, 1, 6, 20} {
+			n := min(len(elements), 1<<levels)
+			tree, err := NewFieldMerkleTree(levels, elements[:n], Element{0})
+			require.NoError(t, err)
+			expected, err := NewMerkleTree(levels, elements[:n], Element{0}, Poseidon)
+			require.NoError(t, err)
+			require.Equal(t, expected.Root(), tree.Root())
+			require.Equal(t, expected.Zeros(), tree.Zeros())
+			require.Equal(t, expected.Elements(), tree.Elements())
+			if levels < 20 {
+				require.Equal(t, expected.Layers(), tree.Layers())
+			}
+			for _, index := range []int{0, n / 2, n - 1} {
+				path, err := tree.Path(index)
+				require.NoError(t, err)
+				expectedPath, err := expected.Path(index)
+				require.NoError(t, err)
+				require.Equal(t, expectedPath, path)
+			}
+		}
+	})
+
+	t.Run("should keep padded leaves", func(t *testing.T) {
+		leaves := make([]Element, 0, 10)
+		for _, leaf := range newTestElements(0, 10) {
+			leaves = append(leaves, padBytes32(leaf))
+		}
+		tree, err := NewFieldMerkleTree(5, leaves[:6], Element{0})
+		require.NoError(t, err)
+		expected, err := NewMerkleTree(5, leaves[:6], Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.NoError(t, tree.BulkInsert(leaves[6:]))
+		require.NoError(t, expected.BulkInsert(leaves[6:]))
+		require.NoError(t, tree.Update(2, Element{0, 0, 9}))
+		require.NoError(t, expected.Update(2, Element{0, 0, 9}))
+
+		require.Equal(t, expected.Elements(), tree.Elements())
+		require.Equal(t, expected.Layers(), tree.Layers())
+		require.Equal(t, expected.IndexOf(leaves[4]), tree.IndexOf(leaves[4]))
+		require.Equal(t, -1, tree.IndexOf(Element{5}))
+		for _, index := range []int{0, 3, 9} {
+			path, err := tree.Path(index)
+			require.NoError(t, err)
+			expectedPath, err := expected.Path(index)
+			require.NoError(t, err)
+			require.Equal(t, expectedPath, path)
+		}
+	})
+
+	t.Run("should insert, update and prove", func(t *testing.T) {
+		tree, err := NewFieldMerkleTree(8, []Element{}, Element{0})
+		require.NoError(t, err)
+		expected, err := NewMerkleTree(8, []Element{}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), tree.Root())
+		for _, element := range elements[:5] {
+			require.NoError(t, tree.Insert(element))
+			require.NoError(t, expected.Insert(element))
+			require.Equal(t, expected.Root(), tree.Root())
+		}
+		require.NoError(t, tree.BulkInsert(elements[5:]))
+		require.NoError(t, expected.BulkInsert(elements[5:]))
+		require.NoError(t, tree.Update(3, Element{7}))
+		require.NoError(t, expected.Update(3, Element{7}))
+		require.NoError(t, tree.Update(39, Element{8}))
+		require.NoError(t, expected.Update(39, Element{8}))
+		require.Equal(t, expected.Layers(), tree.Layers())
+
+		require.Equal(t, 3, tree.IndexOf(Element{7}))
+		require.Equal(t, expected.IndexOf(Element{0, 7}), tree.IndexOf(Element{0, 7}))
+		require.Equal(t, -1, tree.IndexOf(Element{9}))
+		path, err := tree.Proof(Element{7})
+		require.NoError(t, err)
+		require.NoError(t, path.Verify(Element{7}, expected.Root(), Poseidon))
+		_, err = tree.Path(40)
+		require.Error(t, err)
+		require.Error(t, tree.Update(40, Element{1}))
+	})
+
+	t.Run("should reject elements outside the field", func(t *testing.T) {
+		outside := Element(BN254ScalarField.Bytes())
+		_, err := NewFieldMerkleTree(4, nil, outside)
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		require.Equal(t, -1, fieldErr.Index)
+
+		tree, err := NewFieldMerkleTree(2, elements[:1], Element{0})
+		require.NoError(t, err)
+		root := tree.Root()
+		require.ErrorAs(t, tree.BulkInsert([]Element{{1}, outside}), &fieldErr)
+		require.Equal(t, 2, fieldErr.Index)
+		require.Error(t, tree.Update(0, outside))
+		require.Error(t, tree.BulkInsert(elements[:4]))
+		require.Equal(t, root, tree.Root())
+		require.Len(t, tree.Elements(), 1)
+	})
+}
+
+func Benchmark_FieldMerkleTree(b *testing.B) {
+	elements := newTestElements(0, 255)
+	b.Run("field", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = NewFieldMerkleTree(8, elements, Element{0})
+		}
+	})
+	b.Run("big.Int", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = NewMerkleTree(8, elements, Element{0}, Poseidon)
+		}
+	})
+}
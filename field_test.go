@@ -0,0 +1,117 @@
+package fMerkleTree
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FieldModulus(t *testing.T) {
+	p := Element(BN254ScalarField.Bytes())
+	pMinusOne := Element(new(big.Int).Sub(BN254ScalarField, big.NewInt(1)).Bytes())
+
+	t.Run("should reject out of field leaves", func(t *testing.T) {
+		_, err := NewMerkleTree(4, []Element{{1}, p}, Element{0}, Poseidon, WithBN254Field())
+		var fieldErr *FieldError
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, 1, fieldErr.Index)
+		require.Equal(t, p, fieldErr.Element)
+
+		_, err = NewMerkleTree(4, []Element{}, p, Poseidon, WithBN254Field())
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, -1, fieldErr.Index)
+
+		tree, err := NewMerkleTree(4, []Element{{1}, pMinusOne}, Element{0}, Poseidon, WithBN254Field())
+		require.NoError(t, err)
+		root := tree.Root()
+		require.True(t, errors.As(tree.Insert(p), &fieldErr))
+		require.Equal(t, 2, fieldErr.Index)
+		require.True(t, errors.As(tree.Update(0, p), &fieldErr))
+		require.True(t, errors.As(tree.BulkInsert([]Element{{2}, p}), &fieldErr))
+		require.Equal(t, 3, fieldErr.Index)
+		require.Len(t, tree.Elements(), 2)
+		require.Equal(t, root, tree.Root())
+	})
+
+	t.Run("should reject out of field nodes", func(t *testing.T) {
+		leaves := []Element{{1}, {2}, {3}}
+		require.False(t, InField(SHA256Hash(leaves[0], leaves[1]), BN254ScalarField))
+		var fieldErr *FieldError
+		_, err := NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithBN254Field())
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, 1, fieldErr.Level)
+
+		_, err = NewMerkleTree(4, leaves, Element{0}, SHA256Hash, WithBN254Field(), WithHashDomain(RFC6962))
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, 0, fieldErr.Level)
+
+		// leaves {9} hash out of the field, every other node stays inside
+		hashFn := func(left Element, right Element) []byte {
+			if left.Cmp(Element{9}) || right.Cmp(Element{9}) {
+				return p
+			}
+			return SHA256ModP(left, right)
+		}
+		for _, deferred := range []bool{false, true} {
+			opts := []TreeOption{WithBN254Field()}
+			if deferred {
+				opts = append(opts, WithDeferredHashing())
+			}
+			tree, err := NewMerkleTree(4, []Element{{1}, {2}}, Element{0}, hashFn, opts...)
+			require.NoError(t, err)
+			root := tree.Root()
+			for _, write := range []func() error{
+				func() error { return tree.Insert(Element{9}) },
+				func() error { return tree.BulkInsert([]Element{{3}, {9}}) },
+				func() error { return tree.Update(0, Element{9}) },
+				func() error { return tree.BatchUpdate(map[int]Element{1: {9}}) },
+			} {
+				err := write()
+				if err == nil {
+					require.True(t, deferred)
+					err = tree.Commit()
+				}
+				require.True(t, errors.As(err, &fieldErr))
+				require.Equal(t, 1, fieldErr.Level)
+				require.Equal(t, p, fieldErr.Element)
+			}
+			if !deferred {
+				require.Equal(t, root, tree.Root())
+				require.Len(t, tree.Elements(), 2)
+			}
+		}
+	})
+
+	t.Run("should reduce hashes", func(t *testing.T) {
+		left, right := make([]byte, 32), make([]byte, 32)
+		left[31], right[31] = 1, 2
+		digest := sha256.Sum256(append(left, right...))
+		expected := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), BN254ScalarField)
+		require.Equal(t, Element(expected.Bytes()), Element(SHA256ModP(Element{1}, Element{2})))
+
+		reduced := ReduceModP(SHA256Hash, BN254ScalarField)
+		tree, err := NewMerkleTree(8, []Element{{1}, {2}, {3}}, Element{0}, reduced, WithBN254Field())
+		require.NoError(t, err)
+		for _, layer := range tree.Layers() {
+			for _, node := range layer {
+				require.True(t, InField(node, BN254ScalarField))
+			}
+		}
+		require.True(t, InField(tree.Root(), BN254ScalarField))
+	})
+
+	t.Run("should survive serialization", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, []Element{{1}}, Element{0}, SHA256ModP, WithBN254Field())
+		require.NoError(t, err)
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, SHA256ModP)
+		require.NoError(t, err)
+		require.Equal(t, 0, tree2.FieldModulus().Cmp(BN254ScalarField))
+		var fieldErr *FieldError
+		require.True(t, errors.As(tree2.Insert(p), &fieldErr))
+	})
+}
@@ -1,6 +1,7 @@
 package fMerkleTree
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -8,52 +9,103 @@ type MerkleTree struct {
 	*BaseTree
 }
 
-func NewMerkleTree(levels int, elements []Element, zeroElement Element, hashFn HashFunction) (*MerkleTree, error) {
+/**
+* Build a tree with a HashFunction
+* The hash functions of this package, such as Poseidon, are recorded by name and resolved on deserialization.
+* A tree built with any other function has no hash name, so the function must be passed again on deserialization
+ */
+func NewMerkleTree(levels int, elements []Element, zeroElement Element, hashFn HashFunction, opts ...TreeOption) (*MerkleTree, error) {
+	var hasher Hasher
+	if hashFn != nil {
+		hasher = hasherOf(hashFn)
+	}
+	return NewMerkleTreeWithHasher(levels, elements, zeroElement, hasher, opts...)
+}
+
+/**
+* Build a tree with a Hasher, hashing errors are returned instead of panicking
+* @param hasher Hasher for internal nodes, a BatchHasher hashes each layer in one call
+ */
+func NewMerkleTreeWithHasher(levels int, elements []Element, zeroElement Element, hasher Hasher, opts ...TreeOption) (*MerkleTree, error) {
+	return NewMerkleTreeContext(context.Background(), levels, elements, zeroElement, hasher, opts...)
+}
+
+/**
+* Build a tree with a Hasher, the build stops with ctx.Err() when ctx is done
+* Use WithWorkers to hash the layers in parallel
+ */
+func NewMerkleTreeContext(ctx context.Context, levels int, elements []Element, zeroElement Element, hasher Hasher, opts ...TreeOption) (*MerkleTree, error) {
 	base := &BaseTree{levels: levels}
 	if len(elements) > base.Capacity() {
 		return nil, fmt.Errorf("tree is full")
 	}
 
-	if hashFn == nil {
+	if hasher == nil {
 		return nil, fmt.Errorf("hash function is nil")
 	}
 
-	base.hashFn = hashFn
+	base.setHasher(hasher)
+	base.hashName = hasher.Name()
 	base.zeroElement = zeroElement
 	base.layers = make([][]Element, levels+1)
 	base.layers[0] = elements
+	for _, opt := range opts {
+		if err := opt(base); err != nil {
+			return nil, err
+		}
+	}
+	if err := base.checkField(-1, zeroElement); err != nil {
+		return nil, err
+	}
+	for i, element := range elements {
+		if err := base.checkField(i, element); err != nil {
+			return nil, err
+		}
+	}
 	out := &MerkleTree{base}
-	out.buildZeros()
-	out.buildHashes()
+	if err := out.hashLeaves(); err != nil {
+		return nil, err
+	}
+	if out.width > 0 {
+		if err := out.storeLayer(0, out.layers[0]); err != nil {
+			return nil, err
+		}
+	}
+	if err := out.buildZeros(); err != nil {
+		return nil, err
+	}
+	if err := out.buildHashes(ctx); err != nil {
+		return nil, err
+	}
+	out.recordRoot()
 	return out, nil
 }
 
-func (mt *MerkleTree) buildHashes() {
+func (mt *MerkleTree) buildHashes(ctx context.Context) error {
 	for layerIndex := 1; layerIndex <= mt.levels; layerIndex++ {
-		nodes := mt.layers[layerIndex-1]
+		nodes := mt.layer(layerIndex - 1)
 
-		mt.layers[layerIndex] = mt.processNodes(nodes, layerIndex)
-	}
-}
-
-/**
-* Insert multiple elements into the tree.
-* @param elements Elements to insert
- */
-func (mt *MerkleTree) BulkInsert(elements []Element) error {
-	if len(elements) == 0 {
-		return nil
-	}
-	for _, element := range elements {
-		if err := mt.Insert(element); err != nil {
+		layer, err := mt.processNodes(ctx, nodes, layerIndex)
+		if err != nil {
 			return err
 		}
+		if mt.width > 0 {
+			if err := mt.storeLayer(layerIndex, layer); err != nil {
+				return err
+			}
+			continue
+		}
+		mt.layers[layerIndex] = layer
 	}
 	return nil
 }
 
 func (mt MerkleTree) IndexOf(element Element) int {
-	return IndexOfElement(mt.layers[0], element, 0, nil)
+	index := IndexOfElement(mt.leafValues(), element, 0, nil)
+	for mt.skipRemoved && index >= 0 && mt.tombstones[index] {
+		index = IndexOfElement(mt.leafValues(), element, index+1, nil)
+	}
+	return index
 }
 
 func (mt MerkleTree) Proof(element Element) (ProofPath, error) {
@@ -62,10 +114,10 @@ func (mt MerkleTree) Proof(element Element) (ProofPath, error) {
 }
 
 func (mt MerkleTree) getTreeEdge(edgeIndex int) (TreeEdge, error) {
-	if edgeIndex >= len(mt.layers[0]) {
+	if edgeIndex >= mt.layerLen(0) {
 		return TreeEdge{}, fmt.Errorf("index out of range")
 	}
-	edgeElement := mt.layers[0][edgeIndex]
+	edgeElement := mt.detach(mt.leafValue(edgeIndex))
 	if edgeElement == nil {
 		return TreeEdge{}, fmt.Errorf("element not found")
 	}
@@ -77,11 +129,11 @@ func (mt MerkleTree) getTreeEdge(edgeIndex int) (TreeEdge, error) {
 		EdgePath:          edgePath,
 		EdgeElement:       edgeElement,
 		EdgeIndex:         edgeIndex,
-		EdgeElementsCount: len(mt.layers[0])}, nil
+		EdgeElementsCount: mt.layerLen(0)}, nil
 }
 
 func (mt MerkleTree) GetTreeSlices(count int) ([]TreeSlice, error) {
-	length := len(mt.layers[0])
+	length := mt.layerLen(0)
 	size := length / count
 	if length%count != 0 {
 		size++
@@ -89,15 +141,19 @@ func (mt MerkleTree) GetTreeSlices(count int) ([]TreeSlice, error) {
 	if size%2 != 0 {
 		size++
 	}
+	leaves := mt.leafValues()
 	slices := []TreeSlice{}
 	for i := 0; i < length; i += size {
 		edgeLeft := i
 		edgeRight := i + size
+		if edgeRight > length {
+			edgeRight = length
+		}
 		edge, err := mt.getTreeEdge(edgeLeft)
 		if err != nil {
 			return nil, err
 		}
-		slices = append(slices, TreeSlice{Edge: edge, Elements: mt.layers[0][edgeLeft:edgeRight]})
+		slices = append(slices, TreeSlice{Edge: edge, Elements: mt.detachAll(leaves[edgeLeft:edgeRight])})
 	}
 	return slices, nil
 }
@@ -111,7 +167,24 @@ func (mt MerkleTree) Serialize() (SerializedTreeState, error) {
 	return NewSerializedTreeState(&mt)
 }
 
-func DeserializeMerkleTree(data SerializedTreeState, hashFn HashFunction) (*MerkleTree, error) {
+/**
+* Rebuild a serialized tree
+* @param hashFn Hash function the tree was built with, nil to look it up in the hasher registry
+ */
+func DeserializeMerkleTree(data SerializedTreeState, hashFn HashFunction, opts ...TreeOption) (*MerkleTree, error) {
+	var hasher Hasher
+	if hashFn != nil {
+		hasher = hasherOf(hashFn)
+	}
+	return DeserializeMerkleTreeWithHasher(data, hasher, opts...)
+}
+
+func DeserializeMerkleTreeWithHasher(state SerializedTreeState, hasher Hasher, opts ...TreeOption) (*MerkleTree, error) {
+	data := metadataOf(state)
+	hasher, err := resolveHasher(data.GetHashName(), hasher)
+	if err != nil {
+		return nil, err
+	}
 	layers, err := data.GetLayers()
 	if err != nil {
 		fmt.Println("failed to get layers")
@@ -127,13 +200,110 @@ func DeserializeMerkleTree(data SerializedTreeState, hashFn HashFunction) (*Merk
 			levels: data.GetLevels(),
 			layers: layers,
 			zeros:  zeros,
-			hashFn: hashFn,
 		},
 	}
+	out.setHasher(hasher)
+	out.hashName = hasher.Name()
+	if name := data.GetHashName(); name != "" {
+		out.hashName = name
+	}
 	// check against root
 	if !out.Root().Cmp(data.GetRoot()) {
 		return nil, fmt.Errorf("root mismatch")
 	}
-	out.zeroElement = out.zeros[0]
+	out.zeroElement = data.GetZeroElement()
+	if out.zeroElement == nil {
+		out.zeroElement = out.zeros[0]
+	}
+	if data.GetSortedPairs() {
+		opts = append([]TreeOption{WithSortedPairs()}, opts...)
+	}
+	if data.GetNodeWidth() > 0 {
+		opts = append([]TreeOption{WithFixedWidth()}, opts...)
+	}
+	if modulus := data.GetFieldModulus(); modulus != nil {
+		opts = append([]TreeOption{WithFieldModulus(modulus.BigInt())}, opts...)
+	}
+	for _, opt := range opts {
+		if err := opt(out.BaseTree); err != nil {
+			return nil, err
+		}
+	}
+	if name := data.GetDomain(); name != "" && out.domain == nil {
+		domain, ok := LookupHashDomain(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown hash domain %s, pass it with WithHashDomain", name)
+		}
+		if err := WithHashDomain(domain)(out.BaseTree); err != nil {
+			return nil, err
+		}
+	}
+	if err := out.checkFingerprint(data.GetHashFingerprint()); err != nil {
+		return nil, err
+	}
+	if out.width > 0 {
+		for level, layer := range out.layers {
+			if err := out.storeLayer(level, layer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := out.restoreLeaves(data); err != nil {
+		return nil, err
+	}
+	tombstones, err := data.GetTombstones()
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range tombstones {
+		if index < 0 || index >= out.layerLen(0) || !out.leafValue(index).Cmp(out.zeroElement) {
+			return nil, fmt.Errorf("invalid tombstone: %d", index)
+		}
+		if out.tombstones == nil {
+			out.tombstones = make(map[int]bool, len(tombstones))
+		}
+		out.tombstones[index] = true
+	}
+	if size := data.GetRootHistorySize(); size > 0 {
+		entries, err := data.GetRootHistory()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > size {
+			return nil, fmt.Errorf("root history holds %d roots, size is %d", len(entries), size)
+		}
+		out.history = newRootHistory(size)
+		for _, entry := range entries {
+			out.history.push(entry)
+		}
+	}
 	return out, nil
 }
+
+// restoreLeaves loads the elements of a tree built with a hash domain and checks them against layers[0]
+func (mt *MerkleTree) restoreLeaves(data SerializedTreeMetadata) error {
+	name := data.GetDomain()
+	if mt.domain == nil {
+		if name != "" {
+			return fmt.Errorf("tree was built with hash domain %s", name)
+		}
+		return nil
+	}
+	if mt.domain.Name != name {
+		return fmt.Errorf("hash domain mismatch: tree has %q, got %q", name, mt.domain.Name)
+	}
+	leaves, err := data.GetLeaves()
+	if err != nil {
+		return err
+	}
+	if len(leaves) != mt.layerLen(0) {
+		return fmt.Errorf("tree has %d leaves and %d elements", mt.layerLen(0), len(leaves))
+	}
+	for i, leaf := range leaves {
+		if !mt.at(0, i).Cmp(mt.domain.LeafHash(leaf)) {
+			return fmt.Errorf("leaf hash mismatch at %d", i)
+		}
+	}
+	mt.leaves = leaves
+	return nil
+}
@@ -120,8 +120,29 @@ func TestMerkleTree_Serialization(t *testing.T) {
 	tree2, err := DeserializeMerkleTree(data, SHA256Hash)
 	require.NoError(t, err)
 	require.Equal(t, tree.Root(), tree2.Root())
+
+	plain := plainState{levels: 10, root: tree.Root(), layers: tree.Layers(), zeros: tree.Zeros()}
+	tree3, err := DeserializeMerkleTree(plain, SHA256Hash)
+	require.NoError(t, err)
+	require.Equal(t, tree.Layers(), tree3.Layers())
+	require.NoError(t, tree3.Insert(Element{7}))
+	require.NoError(t, tree.Insert(Element{7}))
+	require.Equal(t, tree.Root(), tree3.Root())
 }
 
+// plainState implements only SerializedTreeState, as states stored by callers do
+type plainState struct {
+	levels int
+	root   Element
+	layers [][]Element
+	zeros  []Element
+}
+
+func (s plainState) GetLevels() int                  { return s.levels }
+func (s plainState) GetRoot() Element                { return s.root }
+func (s plainState) GetLayers() ([][]Element, error) { return s.layers, nil }
+func (s plainState) GetZeros() ([]Element, error)    { return s.zeros, nil }
+
 func Test_MerkleTree_Path(t *testing.T) {
 	t.Run("should work for even index", func(t *testing.T) {
 		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {4}, {5}}, Element{0}, SHA256Hash)
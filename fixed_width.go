@@ -0,0 +1,132 @@
+package fMerkleTree
+
+import (
+	"fmt"
+)
+
+// NodeWidth is the width of the node slots in trees built with WithFixedWidth
+const NodeWidth = 32
+
+/**
+* Store every layer in one contiguous buffer of 32-byte slots instead of a slice of nodes
+* A node is kept right-aligned in its slot and its length next to the buffer, so nodes are hashed and
+* returned with the same bytes as without this option and the tree does not change.
+* Nodes longer than NodeWidth are rejected. Every node returned, by Layers, Root, the proofs or the trees
+* built from this one, is a copy that later updates do not change.
+ */
+func WithFixedWidth() TreeOption {
+	return func(bt *BaseTree) error {
+		bt.width = NodeWidth
+		return nil
+	}
+}
+
+func (bt BaseTree) FixedWidth() bool {
+	return bt.width > 0
+}
+
+// checkWidth rejects nodes that do not fit in a slot, every node fits without a fixed width
+func (bt BaseTree) checkWidth(node Element) error {
+	if bt.width > 0 && len(node) > bt.width {
+		return fmt.Errorf("node of %d bytes does not fit in %d", len(node), bt.width)
+	}
+	return nil
+}
+
+// detach copies a node out of the layer buffers so that later updates do not change it
+func (bt BaseTree) detach(node Element) Element {
+	if bt.width == 0 || node == nil {
+		return node
+	}
+	return append(Element{}, node...)
+}
+
+// detachAll returns nodes, copied in fixed width mode
+func (bt BaseTree) detachAll(nodes []Element) []Element {
+	if bt.width == 0 {
+		return nodes
+	}
+	out := make([]Element, len(nodes))
+	for i, node := range nodes {
+		out[i] = bt.detach(node)
+	}
+	return out
+}
+
+// storeLayer copies nodes into a new contiguous buffer, the layer itself is not kept
+func (bt *BaseTree) storeLayer(level int, nodes []Element) error {
+	if bt.storage == nil {
+		bt.storage = make([][]byte, bt.levels+1)
+		bt.lengths = make([][]uint8, bt.levels+1)
+	}
+	buf := make([]byte, len(nodes)*bt.width)
+	lengths := make([]uint8, len(nodes))
+	for i, node := range nodes {
+		if err := bt.checkWidth(node); err != nil {
+			return err
+		}
+		copy(buf[(i+1)*bt.width-len(node):], node)
+		lengths[i] = uint8(len(node))
+	}
+	bt.storage[level] = buf
+	bt.lengths[level] = lengths
+	bt.layers[level] = nil
+	return nil
+}
+
+// view returns the node at index as a slice of its slot
+func (bt BaseTree) view(level, index int) Element {
+	end := (index + 1) * bt.width
+	return bt.storage[level][end-int(bt.lengths[level][index]) : end : end]
+}
+
+// setNode writes node into the layer buffer, growing it when index is past its end
+func (bt *BaseTree) setNode(level, index int, node Element) error {
+	if err := bt.checkWidth(node); err != nil {
+		return err
+	}
+	if index >= len(bt.lengths[level]) {
+		end := (index + 1) * bt.width
+		if buf := bt.storage[level]; end > cap(buf) {
+			grown := make([]byte, end, max(2*cap(buf), end))
+			copy(grown, buf)
+			bt.storage[level] = grown
+		} else {
+			bt.storage[level] = buf[:end]
+		}
+		bt.lengths[level] = append(bt.lengths[level], make([]uint8, index+1-len(bt.lengths[level]))...)
+	}
+	slot := bt.storage[level][index*bt.width : (index+1)*bt.width]
+	clear(slot[:bt.width-len(node)])
+	copy(slot[bt.width-len(node):], node)
+	bt.lengths[level][index] = uint8(len(node))
+	return nil
+}
+
+// layerLen returns the number of nodes stored in a layer
+func (bt BaseTree) layerLen(level int) int {
+	if bt.width > 0 {
+		return len(bt.lengths[level])
+	}
+	return len(bt.layers[level])
+}
+
+// at returns a stored node, in fixed width mode a view of its slot that later writes change
+func (bt BaseTree) at(level, index int) Element {
+	if bt.width > 0 {
+		return bt.view(level, index)
+	}
+	return bt.layers[level][index]
+}
+
+// layer returns the nodes of a level, in fixed width mode views of their slots
+func (bt BaseTree) layer(level int) []Element {
+	if bt.width == 0 {
+		return bt.layers[level]
+	}
+	out := make([]Element, len(bt.lengths[level]))
+	for index := range out {
+		out[index] = bt.view(level, index)
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package fMerkleTree
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FixedWidth(t *testing.T) {
+	elements := make([]Element, 0, 40)
+	for i := 1; i <= 40; i++ {
+		elements = append(elements, padBytes32(Poseidon(Element{byte(i)}, Element{0})))
+	}
+
+	t.Run("should keep layers in contiguous buffers", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, elements, Element{0}, Poseidon, WithFixedWidth())
+		require.NoError(t, err)
+		require.True(t, tree.FixedWidth())
+		for level, layer := range tree.Layers() {
+			require.Len(t, tree.storage[level], len(layer)*NodeWidth)
+			for i, node := range layer {
+				require.Equal(t, padBytes32(node), tree.storage[level][i*NodeWidth:(i+1)*NodeWidth])
+			}
+		}
+
+		plain, err := NewMerkleTree(8, elements, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, plain.Root(), tree.Root())
+		require.Equal(t, plain.Layers(), tree.Layers())
+	})
+
+	t.Run("should not change the tree", func(t *testing.T) {
+		for _, hashFn := range []HashFunction{SHA256Hash, Poseidon} {
+			leaves := newTestElements(0, 21)
+			tree, err := NewMerkleTree(6, leaves, Element{0}, hashFn, WithFixedWidth())
+			require.NoError(t, err)
+			plain, err := NewMerkleTree(6, leaves, Element{0}, hashFn)
+			require.NoError(t, err)
+			require.NoError(t, tree.Update(3, Element{0, 7}))
+			require.NoError(t, plain.Update(3, Element{0, 7}))
+			require.NoError(t, tree.BulkInsert([]Element{{30}, {31}}))
+			require.NoError(t, plain.BulkInsert([]Element{{30}, {31}}))
+
+			require.Equal(t, plain.Root(), tree.Root())
+			require.Equal(t, plain.Zeros(), tree.Zeros())
+			require.Equal(t, plain.Layers(), tree.Layers())
+			require.Equal(t, plain.Elements(), tree.Elements())
+			for _, index := range []int{0, 3, 22} {
+				expected, err := plain.Path(index)
+				require.NoError(t, err)
+				path, err := tree.Path(index)
+				require.NoError(t, err)
+				require.Equal(t, expected, path)
+			}
+		}
+	})
+
+	t.Run("should insert, update and prove", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, []Element{}, Element{0}, Poseidon, WithFixedWidth(), WithRootHistory(100))
+		require.NoError(t, err)
+		plain, err := NewMerkleTree(8, []Element{}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		for _, element := range elements {
+			root := tree.Root()
+			require.NoError(t, tree.Insert(element[31:]))
+			require.NoError(t, plain.Insert(element[31:]))
+			require.True(t, tree.IsKnownRoot(root))
+			require.NotEqual(t, root, tree.Root())
+		}
+		require.NoError(t, tree.Update(3, Element{7}))
+		require.NoError(t, plain.Update(3, Element{7}))
+		require.Equal(t, plain.Root(), tree.Root())
+		require.Len(t, tree.storage[0], len(elements)*NodeWidth)
+
+		require.Equal(t, 3, tree.IndexOf(Element{7}))
+		path, err := tree.Proof(Element{7})
+		require.NoError(t, err)
+		require.NoError(t, tree.VerifyProof(Element{7}, path))
+		require.NoError(t, path.Verify(Element{7}, tree.Root(), Poseidon))
+		root := tree.Root()
+		require.NoError(t, tree.Remove(3))
+		require.Equal(t, root, path.PathRoot)
+		require.NoError(t, path.Verify(Element{7}, root, Poseidon))
+
+		require.Error(t, tree.Insert(make(Element, 33)))
+		require.Panics(t, func() { tree.SetLayer(0, 0, make(Element, 33)) })
+		_, err = NewMerkleTree(8, []Element{make(Element, 33)}, Element{0}, Poseidon, WithFixedWidth())
+		require.Error(t, err)
+	})
+
+	t.Run("should return copies", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, newTestElements(0, 4), Element{0}, SHA256Hash, WithFixedWidth())
+		require.NoError(t, err)
+		leaves := tree.Elements()
+		layers := tree.Layers()
+		require.NoError(t, tree.Update(0, Element{9}))
+		require.Equal(t, Element{1}, leaves[0])
+		require.Equal(t, Element{1}, layers[0][0])
+		require.NotEqual(t, tree.Root(), layers[4][0])
+	})
+
+	t.Run("should not change returned proofs on update", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, newTestElements(0, 5), Element{0}, SHA256Hash, WithFixedWidth())
+		require.NoError(t, err)
+		multi, err := tree.MultiProof([]int{0})
+		require.NoError(t, err)
+		consistency, err := tree.ConsistencyProof(1, 5)
+		require.NoError(t, err)
+		mmr, err := NewMerkleMountainRangeFrom(tree)
+		require.NoError(t, err)
+		incremental, err := NewIncrementalMerkleTreeFrom(tree)
+		require.NoError(t, err)
+
+		require.NoError(t, tree.Update(1, Element{9}))
+		require.NoError(t, tree.Update(4, Element{9}))
+		require.Equal(t, Element{2}, multi.Siblings[0])
+		require.Equal(t, Element{2}, consistency.Leaf)
+		require.Equal(t, newTestElements(0, 5), mmr.Elements())
+		require.NoError(t, incremental.Insert(Element{6}))
+		expected, err := NewMerkleTree(4, newTestElements(0, 6), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), incremental.Root())
+	})
+
+	t.Run("should use less memory", func(t *testing.T) {
+		heap := func(opts ...TreeOption) uint64 {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			leaves := make([]Element, 1<<16)
+			for i := range leaves {
+				leaves[i] = SHA256Hash(Element{byte(i >> 8)}, Element{byte(i)})
+			}
+			tree, err := NewMerkleTree(16, leaves, Element{0}, SHA256Hash, opts...)
+			require.NoError(t, err)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(tree)
+			return after.HeapAlloc - before.HeapAlloc
+		}
+		plain := heap()
+		fixed := heap(WithFixedWidth())
+		// a plain node is a slice header in its layer and a separate 32-byte array, a fixed width node is its slot and length
+		require.Less(t, fixed, plain*2/3)
+	})
+
+	t.Run("should serialize smaller", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, elements, Element{0}, Poseidon, WithFixedWidth())
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(1))
+		require.NoError(t, tree.Insert(Element{1}))
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		plain, err := NewMerkleTree(8, elements, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.NoError(t, plain.Remove(1))
+		require.NoError(t, plain.Insert(Element{1}))
+		plainData, err := plain.Serialize()
+		require.NoError(t, err)
+		state := data.(*serializedTreeState)
+		require.Less(t, len(state.Layers)+len(state.ShortNodes), len(plainData.(*serializedTreeState).Layers))
+
+		tree2, err := DeserializeMerkleTree(data, Poseidon)
+		require.NoError(t, err)
+		require.True(t, tree2.FixedWidth())
+		require.True(t, tree2.IsRemoved(1))
+		require.Equal(t, tree.Layers(), tree2.Layers())
+		require.NoError(t, tree.Insert(Element{2}))
+		require.NoError(t, tree2.Insert(Element{2}))
+		require.Equal(t, tree.Root(), tree2.Root())
+	})
+}
@@ -0,0 +1,131 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0xbow-io/go-iden3-crypto/poseidon"
+)
+
+var (
+	SHA256Hasher     = NewHasher("sha256", 32, SHA256Hash)
+	Keccak256Hasher  = NewHasher("keccak256", 32, Keccak256)
+	MIMC7Hasher      = NewHasher("mimc7", 32, MIMC7)
+	SHA256ModPHasher = NewHasher("sha256-mod-p", 32, SHA256ModP)
+	PoseidonHasher   = Hasher(poseidonHasher{name: "poseidon", hash: poseidon.Hash})
+	Poseidon2Hasher  = Hasher(poseidonHasher{name: "poseidon2", hash: poseidon.Poseidon2})
+)
+
+type funcHasher struct {
+	name   string
+	size   int
+	hashFn HashFunction
+}
+
+/**
+* Adapt a HashFunction to the Hasher interface
+* A panic inside hashFn is returned as an error
+* @param name Name of the hash function
+* @param outputSize Maximum length of a hash in bytes, 0 if unknown
+ */
+func NewHasher(name string, outputSize int, hashFn HashFunction) Hasher {
+	return funcHasher{name: name, size: outputSize, hashFn: hashFn}
+}
+
+func (h funcHasher) Hash(left Element, right Element) (out Element, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hash function %s: %v", h.name, r)
+		}
+	}()
+	return h.hashFn(left, right), nil
+}
+
+func (h funcHasher) Name() string {
+	return h.name
+}
+
+func (h funcHasher) OutputSize() int {
+	return h.size
+}
+
+// HashFunc adapts a Hasher to a HashFunction, which panics when hashing fails
+func HashFunc(hasher Hasher) HashFunction {
+	if h, ok := hasher.(funcHasher); ok {
+		return h.hashFn
+	}
+	return func(left Element, right Element) []byte {
+		out, err := hasher.Hash(left, right)
+		if err != nil {
+			panic(err.Error())
+		}
+		return out
+	}
+}
+
+// hashPairs hashes nodes[2i] with nodes[2i+1], in one call if hasher is a BatchHasher
+func hashPairs(hasher Hasher, nodes []Element) ([]Element, error) {
+	if batch, ok := hasher.(BatchHasher); ok {
+		out, err := batch.HashBatch(nodes)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != len(nodes)/2 {
+			return nil, fmt.Errorf("%s: batch returned %d hashes for %d pairs", hasher.Name(), len(out), len(nodes)/2)
+		}
+		return out, nil
+	}
+	out := make([]Element, len(nodes)/2)
+	for i := range out {
+		hash, err := hasher.Hash(nodes[i*2], nodes[i*2+1])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = hash
+	}
+	return out, nil
+}
+
+type poseidonHasher struct {
+	name string
+	hash func(inputs []*big.Int) (*big.Int, error)
+}
+
+func (h poseidonHasher) Hash(left Element, right Element) (Element, error) {
+	result, err := h.hash([]*big.Int{left.BigInt(), right.BigInt()})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", h.name, err)
+	}
+	return result.Bytes(), nil
+}
+
+func (h poseidonHasher) Name() string {
+	return h.name
+}
+
+func (h poseidonHasher) OutputSize() int {
+	return 32
+}
+
+type sortedPairHasher struct {
+	Hasher
+}
+
+// SortedPairHasher wraps hasher so that each pair is sorted before hashing, see SortedPairHash
+func SortedPairHasher(hasher Hasher) Hasher {
+	return sortedPairHasher{hasher}
+}
+
+func (h sortedPairHasher) Hash(left Element, right Element) (Element, error) {
+	if left.BigInt().Cmp(right.BigInt()) > 0 {
+		left, right = right, left
+	}
+	return h.Hasher.Hash(left, right)
+}
+
+func (h sortedPairHasher) Name() string {
+	if h.Hasher.Name() == "" {
+		return ""
+	}
+	return "sorted-" + h.Hasher.Name()
+}
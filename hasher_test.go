@@ -0,0 +1,154 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// batchHasher counts calls to show that layers are hashed in one batch
+type batchHasher struct {
+	Hasher
+	batches *int
+}
+
+func (h batchHasher) HashBatch(nodes []Element) ([]Element, error) {
+	*h.batches++
+	out := make([]Element, len(nodes)/2)
+	for i := range out {
+		out[i] = SHA256Hash(nodes[i*2], nodes[i*2+1])
+	}
+	return out, nil
+}
+
+// failingHasher fails on a given left input
+type failingHasher struct {
+	Hasher
+	fail Element
+}
+
+func (h failingHasher) Hash(left Element, right Element) (Element, error) {
+	if left.Cmp(h.fail) {
+		return nil, fmt.Errorf("cannot hash %s", left.Hex())
+	}
+	return h.Hasher.Hash(left, right)
+}
+
+func Test_Hasher(t *testing.T) {
+	outOfField := Element(BN254ScalarField.Bytes())
+
+	t.Run("should return errors instead of panicking", func(t *testing.T) {
+		_, err := PoseidonHasher.Hash(outOfField, Element{1})
+		require.Error(t, err)
+		require.Panics(t, func() { Poseidon(outOfField, Element{1}) })
+
+		_, err = NewMerkleTree(4, []Element{{1}, outOfField}, Element{0}, Poseidon)
+		require.Error(t, err)
+		_, err = NewMerkleTreeWithHasher(4, []Element{{1}, outOfField}, Element{0}, PoseidonHasher)
+		require.Error(t, err)
+		_, err = NewMerkleTreeWithHasher(4, []Element{}, outOfField, PoseidonHasher)
+		require.Error(t, err)
+
+		tree, err := NewMerkleTreeWithHasher(4, []Element{{1}, {2}}, Element{0}, PoseidonHasher)
+		require.NoError(t, err)
+		root := tree.Root()
+		require.Error(t, tree.Insert(outOfField))
+		require.Error(t, tree.Update(0, outOfField))
+		require.Error(t, tree.BulkInsert([]Element{{3}, outOfField}))
+		require.Len(t, tree.Elements(), 2)
+		require.NoError(t, tree.Insert(Element{3}))
+		require.NoError(t, tree.Remove(2))
+		require.NoError(t, tree.Update(2, Element{3}))
+		require.Equal(t, []Element{{1}, {2}, {3}}, tree.Elements())
+
+		expected, err := NewMerkleTree(4, []Element{{1}, {2}, {3}}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), tree.Root())
+		require.NotEqual(t, root, tree.Root())
+	})
+
+	t.Run("should return errors from proofs", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(2, []Element{{1}, {2}, {3}, {4}}, Element{0}, PoseidonHasher)
+		require.NoError(t, err)
+		path, err := tree.Path(0)
+		require.NoError(t, err)
+		require.NoError(t, path.VerifyWithHasher(Element{1}, tree.Root(), PoseidonHasher))
+		require.Error(t, path.Verify(outOfField, tree.Root(), Poseidon))
+		require.Error(t, path.VerifyWithHasher(outOfField, tree.Root(), PoseidonHasher))
+
+		multi, err := tree.MultiProof([]int{0, 3})
+		require.NoError(t, err)
+		require.NoError(t, multi.VerifyWithHasher([]Element{{1}, {4}}, tree.Root(), PoseidonHasher))
+		require.Error(t, multi.Verify([]Element{{1}, outOfField}, tree.Root(), Poseidon))
+
+		consistency, err := tree.ConsistencyProof(1, 4)
+		require.NoError(t, err)
+		old, err := NewMerkleTree(2, []Element{{1}}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.NoError(t, VerifyConsistencyWithHasher(old.Root(), tree.Root(), 1, 4, consistency, PoseidonHasher))
+		consistency.Leaf = outOfField
+		require.Error(t, VerifyConsistency(old.Root(), tree.Root(), 1, 4, consistency, Poseidon))
+
+		// hashing a leaf with the zero element fails, full trees never do
+		padded := NewHasher("test-padded", 32, func(left Element, right Element) []byte {
+			if right.Cmp(Element{0}) && !left.Cmp(Element{0}) {
+				panic("cannot pad")
+			}
+			return SHA256Hash(left, right)
+		})
+		full, err := NewMerkleTreeWithHasher(2, []Element{{1}, {2}, {3}, {4}}, Element{0}, padded)
+		require.NoError(t, err)
+		_, err = full.ConsistencyProof(1, 3)
+		require.ErrorContains(t, err, "cannot pad")
+	})
+
+	t.Run("should leave the tree unchanged on errors", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, []Element{{1}, {2}}, Element{0}, failingHasher{SHA256Hasher, Element{9}})
+		require.NoError(t, err)
+		root, layers := tree.Root(), len(tree.Layers()[0])
+		require.Error(t, tree.Insert(Element{9}))
+		require.Error(t, tree.Update(0, Element{9}))
+		require.Equal(t, root, tree.Root())
+		require.Len(t, tree.Layers()[0], layers)
+		require.Equal(t, Element{1}, tree.Elements()[0])
+	})
+
+	t.Run("should hash layers in batches", func(t *testing.T) {
+		batches := 0
+		elements := []Element{{1}, {2}, {3}, {4}, {5}}
+		tree, err := NewMerkleTreeWithHasher(6, elements, Element{0}, batchHasher{SHA256Hasher, &batches})
+		require.NoError(t, err)
+		require.Equal(t, 6, batches)
+		expected, err := NewMerkleTree(6, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), tree.Root())
+	})
+
+	t.Run("should adapt hash functions", func(t *testing.T) {
+		hasher := NewHasher("sha256", 32, SHA256Hash)
+		require.Equal(t, "sha256", hasher.Name())
+		require.Equal(t, 32, hasher.OutputSize())
+		hash, err := hasher.Hash(Element{1}, Element{2})
+		require.NoError(t, err)
+		require.Equal(t, Element(SHA256Hash(Element{1}, Element{2})), hash)
+		require.Equal(t, hash, Element(HashFunc(hasher)(Element{1}, Element{2})))
+
+		sorted := SortedPairHasher(Keccak256Hasher)
+		require.Equal(t, "sorted-keccak256", sorted.Name())
+		hash, err = sorted.Hash(Element{2}, Element{1})
+		require.NoError(t, err)
+		require.Equal(t, Element(Keccak256(Element{1}, Element{2})), hash)
+
+		tree, err := NewMerkleTreeWithHasher(4, []Element{{1}}, Element{0}, Keccak256Hasher, WithSortedPairs())
+		require.NoError(t, err)
+		require.Equal(t, "sorted-keccak256", tree.Hasher().Name())
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTreeWithHasher(data, Keccak256Hasher)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{2}))
+		require.NoError(t, tree2.Insert(Element{2}))
+		require.Equal(t, tree.Root(), tree2.Root())
+	})
+}
@@ -0,0 +1,218 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+/**
+* Append-only merkle tree that only keeps the filled subtrees frontier, as in
+* Tornado's MerkleTreeWithHistory.sol. Memory is O(levels) plus one path per tracked leaf.
+ */
+type IncrementalMerkleTree struct {
+	levels         int
+	hashFn         HashFunction
+	zeroElement    Element
+	zeros          []Element
+	filledSubtrees []Element
+	nextIndex      int
+	root           Element
+	tracked        map[int]*ProofPath
+}
+
+func NewIncrementalMerkleTree(levels int, zeroElement Element, hashFn HashFunction) (*IncrementalMerkleTree, error) {
+	if levels < 1 || levels >= 63 {
+		return nil, fmt.Errorf("invalid levels: %d", levels)
+	}
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	zeros := buildZeros(levels, zeroElement, hashFn)
+	return &IncrementalMerkleTree{
+		levels:         levels,
+		hashFn:         hashFn,
+		zeroElement:    zeroElement,
+		zeros:          zeros,
+		filledSubtrees: append([]Element(nil), zeros[:levels]...),
+		root:           zeros[levels],
+		tracked:        make(map[int]*ProofPath),
+	}, nil
+}
+
+/**
+* Build the frontier of a full tree
+* @param mt Source tree
+* @param track Leaf indices whose paths should be kept up to date
+ */
+func NewIncrementalMerkleTreeFrom(mt *MerkleTree, track ...int) (*IncrementalMerkleTree, error) {
+	if mt.domain != nil {
+		return nil, fmt.Errorf("trees with a hash domain are not supported")
+	}
+	if err := mt.Commit(); err != nil {
+		return nil, err
+	}
+	out, err := NewIncrementalMerkleTree(mt.levels, mt.zeroElement, mt.hashFn)
+	if err != nil {
+		return nil, err
+	}
+	out.nextIndex = mt.layerLen(0)
+	out.root = mt.Root()
+	for level := 0; level < mt.levels; level++ {
+		if index := out.nextIndex >> level; index%2 == 1 {
+			out.filledSubtrees[level] = mt.detach(mt.at(level, index-1))
+		}
+	}
+	for _, index := range track {
+		path, err := mt.Path(index)
+		if err != nil {
+			return nil, err
+		}
+		out.tracked[index] = &path
+	}
+	return out, nil
+}
+
+/**
+* Rebuild the full tree
+* @param elements All leaves inserted so far, checked against the current root
+ */
+func (it IncrementalMerkleTree) ToMerkleTree(elements []Element) (*MerkleTree, error) {
+	if len(elements) != it.nextIndex {
+		return nil, fmt.Errorf("expected %d elements, got %d", it.nextIndex, len(elements))
+	}
+	mt, err := NewMerkleTree(it.levels, elements, it.zeroElement, it.hashFn)
+	if err != nil {
+		return nil, err
+	}
+	if !mt.Root().Cmp(it.root) {
+		return nil, fmt.Errorf("root mismatch")
+	}
+	return mt, nil
+}
+
+func (it IncrementalMerkleTree) Capacity() int {
+	return 1 << it.levels
+}
+
+func (it IncrementalMerkleTree) Size() int {
+	return it.nextIndex
+}
+
+func (it IncrementalMerkleTree) Root() Element {
+	return it.root
+}
+
+func (it IncrementalMerkleTree) Zeros() []Element {
+	return it.zeros
+}
+
+/**
+* Insert new element into the tree
+* @param element Element to insert
+ */
+func (it *IncrementalMerkleTree) Insert(element Element) error {
+	_, err := it.insert(element)
+	return err
+}
+
+/**
+* Insert new element and keep its path up to date on later inserts
+* @returns index of the inserted element
+ */
+func (it *IncrementalMerkleTree) InsertTracked(element Element) (int, error) {
+	path, err := it.insert(element)
+	if err != nil {
+		return -1, err
+	}
+	it.tracked[it.nextIndex-1] = &path
+	return it.nextIndex - 1, nil
+}
+
+func (it *IncrementalMerkleTree) insert(element Element) (ProofPath, error) {
+	if it.nextIndex >= it.Capacity() {
+		return ProofPath{}, fmt.Errorf("tree is full")
+	}
+	index := it.nextIndex
+	path := ProofPath{
+		PathElements:  make([]Element, it.levels),
+		PathIndices:   make([]int, it.levels),
+		PathPositions: make([]int, it.levels),
+	}
+	// nodes[level] is the node at level containing the new leaf
+	nodes := make([]Element, it.levels)
+	current := element
+	for level := 0; level < it.levels; level++ {
+		nodes[level] = current
+		path.PathIndices[level] = index % 2
+		if index%2 == 0 {
+			it.filledSubtrees[level] = current
+			path.PathElements[level] = it.zeros[level]
+			current = it.hashFn(current, it.zeros[level])
+		} else {
+			path.PathElements[level] = it.filledSubtrees[level]
+			path.PathPositions[level] = index ^ 1
+			current = it.hashFn(it.filledSubtrees[level], current)
+		}
+		index >>= 1
+	}
+	it.root = current
+	path.PathRoot = current
+
+	for leaf, tracked := range it.tracked {
+		// the new leaf only changes the sibling at the level where both paths join
+		level := bits.Len(uint(leaf^it.nextIndex)) - 1
+		tracked.PathElements[level] = nodes[level]
+		tracked.PathPositions[level] = (leaf >> level) ^ 1
+		tracked.PathRoot = current
+	}
+	it.nextIndex++
+	return path, nil
+}
+
+/**
+* Insert multiple elements into the tree.
+* @param elements Elements to insert
+ */
+func (it *IncrementalMerkleTree) BulkInsert(elements []Element) error {
+	if it.nextIndex+len(elements) > it.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	for _, element := range elements {
+		if err := it.Insert(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+* Get merkle path to a tracked leaf
+* @param index Leaf index returned by InsertTracked or passed to NewIncrementalMerkleTreeFrom
+ */
+func (it IncrementalMerkleTree) Path(index int) (ProofPath, error) {
+	tracked, ok := it.tracked[index]
+	if !ok {
+		return ProofPath{}, fmt.Errorf("leaf is not tracked: %d", index)
+	}
+	return ProofPath{
+		PathElements:  append([]Element(nil), tracked.PathElements...),
+		PathIndices:   append([]int(nil), tracked.PathIndices...),
+		PathPositions: append([]int(nil), tracked.PathPositions...),
+		PathRoot:      tracked.PathRoot,
+	}, nil
+}
+
+// Tracked returns the tracked leaf indices in ascending order
+func (it IncrementalMerkleTree) Tracked() []int {
+	out := make([]int, 0, len(it.tracked))
+	for index := range it.tracked {
+		out = append(out, index)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func (it *IncrementalMerkleTree) Untrack(index int) {
+	delete(it.tracked, index)
+}
@@ -0,0 +1,82 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IncrementalMerkleTree(t *testing.T) {
+	t.Run("should have the same roots as MerkleTree", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		incremental, err := NewIncrementalMerkleTree(10, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), incremental.Root())
+		for i := 1; i <= 37; i++ {
+			require.NoError(t, tree.Insert(Element{byte(i)}))
+			require.NoError(t, incremental.Insert(Element{byte(i)}))
+			require.Equal(t, tree.Root(), incremental.Root())
+		}
+		require.Equal(t, 37, incremental.Size())
+	})
+
+	t.Run("should keep tracked paths up to date", func(t *testing.T) {
+		incremental, err := NewIncrementalMerkleTree(6, Element{0}, Poseidon)
+		require.NoError(t, err)
+		elements := []Element{}
+		for i := 1; i <= 23; i++ {
+			elements = append(elements, Element{byte(i)})
+			if i%5 == 1 {
+				_, err := incremental.InsertTracked(Element{byte(i)})
+				require.NoError(t, err)
+			} else {
+				require.NoError(t, incremental.Insert(Element{byte(i)}))
+			}
+		}
+		require.Equal(t, []int{0, 5, 10, 15, 20}, incremental.Tracked())
+
+		tree, err := incremental.ToMerkleTree(elements)
+		require.NoError(t, err)
+		for _, index := range incremental.Tracked() {
+			expected, err := tree.Path(index)
+			require.NoError(t, err)
+			path, err := incremental.Path(index)
+			require.NoError(t, err)
+			require.Equal(t, expected, path)
+		}
+		incremental.Untrack(5)
+		_, err = incremental.Path(5)
+		require.Error(t, err)
+	})
+
+	t.Run("should convert from a full tree", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, newTestElements(0, 13), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		incremental, err := NewIncrementalMerkleTreeFrom(tree, 3, 12)
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), incremental.Root())
+		for i := 13; i < 40; i++ {
+			require.NoError(t, tree.Insert(Element{byte(i + 1)}))
+			require.NoError(t, incremental.Insert(Element{byte(i + 1)}))
+			require.Equal(t, tree.Root(), incremental.Root())
+		}
+		expected, err := tree.Path(3)
+		require.NoError(t, err)
+		path, err := incremental.Path(3)
+		require.NoError(t, err)
+		require.Equal(t, expected, path)
+
+		_, err = incremental.ToMerkleTree(newTestElements(0, 40))
+		require.NoError(t, err)
+		_, err = incremental.ToMerkleTree(newTestElements(1, 41))
+		require.Error(t, err)
+	})
+
+	t.Run("should fail when full", func(t *testing.T) {
+		incremental, err := NewIncrementalMerkleTree(2, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, incremental.BulkInsert([]Element{{1}, {2}, {3}, {4}}))
+		require.Error(t, incremental.Insert(Element{5}))
+	})
+}
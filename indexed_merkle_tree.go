@@ -0,0 +1,356 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+/**
+* Leaf of an indexed merkle tree.
+* Leaves form a linked list sorted by value, NextIndex 0 with NextValue 0 marks the end of the list.
+ */
+type IndexedLeaf struct {
+	Value     Element `json:"value"`
+	NextIndex int     `json:"nextIndex"`
+	NextValue Element `json:"nextValue"`
+}
+
+/**
+* Hash of an indexed leaf: leafHashFn(value, nextIndex, nextValue), as in Aztec's indexed merkle tree
+* @param leafHashFn 3-input hash function the tree was built with, e.g. PoseidonN
+ */
+func (l IndexedLeaf) Hash(leafHashFn NaryHashFunction) Element {
+	return leafHashFn([]Element{l.Value, indexElement(l.NextIndex), l.NextValue})
+}
+
+// hash calls Hash and returns a panic inside leafHashFn, e.g. PoseidonN on a value outside the field, as an error
+func (l IndexedLeaf) hash(leafHashFn NaryHashFunction) (out Element, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("leaf hash function: %v", r)
+		}
+	}()
+	return l.Hash(leafHashFn), nil
+}
+
+func (l IndexedLeaf) isLast() bool {
+	return l.NextIndex == 0 && l.NextValue.BigInt().Sign() == 0
+}
+
+// indexElement encodes a leaf index as a big-endian element of at least one byte
+func indexElement(index int) Element {
+	out := big.NewInt(int64(index)).Bytes()
+	if len(out) == 0 {
+		return Element{0}
+	}
+	return out
+}
+
+// IndexedMembershipProof proves that Leaf is stored at Index
+type IndexedMembershipProof struct {
+	Leaf  IndexedLeaf `json:"leaf"`
+	Index int         `json:"index"`
+	Path  ProofPath   `json:"path"`
+}
+
+/**
+* Everything a circuit needs to check a single insertion.
+* LowLeafPath is taken against the root before the insertion, NewLeafPath against the root
+* after it. The siblings of NewLeafPath are the same before the new leaf is written, so the
+* slot can be shown to have been empty.
+ */
+type IndexedInsertionWitness struct {
+	Value        Element     `json:"value"`
+	LowLeaf      IndexedLeaf `json:"lowLeaf"`
+	LowLeafIndex int         `json:"lowLeafIndex"`
+	LowLeafPath  ProofPath   `json:"lowLeafPath"`
+	NewLeaf      IndexedLeaf `json:"newLeaf"`
+	NewLeafIndex int         `json:"newLeafIndex"`
+	NewLeafPath  ProofPath   `json:"newLeafPath"`
+}
+
+/**
+* Indexed merkle tree for nullifier sets.
+* Leaf hashes are appended to an ordinary MerkleTree, index 0 holds the zero value sentinel.
+* A value is absent when a low leaf with Value < value < NextValue exists.
+* The underlying tree is only changed through the indexed operations, which keep the linked list intact.
+ */
+type IndexedMerkleTree struct {
+	tree       *MerkleTree
+	leafHashFn NaryHashFunction
+	leaves     []IndexedLeaf
+	// leaf indices sorted by value
+	sorted []int
+}
+
+/**
+* Create an indexed tree holding the zero value sentinel
+* @param hashFn Hash function of the tree nodes, e.g. Poseidon
+* @param leafHashFn 3-input hash function of the leaves, e.g. PoseidonN
+ */
+func NewIndexedMerkleTree(levels int, zeroElement Element, hashFn HashFunction, leafHashFn NaryHashFunction) (*IndexedMerkleTree, error) {
+	if leafHashFn == nil {
+		return nil, fmt.Errorf("leaf hash function is nil")
+	}
+	tree, err := NewMerkleTree(levels, []Element{}, zeroElement, hashFn)
+	if err != nil {
+		return nil, err
+	}
+	out := &IndexedMerkleTree{tree: tree, leafHashFn: leafHashFn}
+	sentinel := IndexedLeaf{Value: Element{0}, NextValue: Element{0}}
+	node, err := sentinel.hash(leafHashFn)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.tree.Insert(node); err != nil {
+		return nil, err
+	}
+	out.leaves = []IndexedLeaf{sentinel}
+	out.sorted = []int{0}
+	return out, nil
+}
+
+func (it IndexedMerkleTree) Root() Element {
+	return it.tree.Root()
+}
+
+func (it IndexedMerkleTree) Capacity() int {
+	return it.tree.Capacity()
+}
+
+// Elements returns the leaf hashes
+func (it IndexedMerkleTree) Elements() []Element {
+	return append([]Element{}, it.tree.Elements()...)
+}
+
+// Layers returns copies of the layers of the underlying tree
+func (it IndexedMerkleTree) Layers() [][]Element {
+	layers := it.tree.Layers()
+	out := make([][]Element, len(layers))
+	for level, layer := range layers {
+		out[level] = append([]Element{}, layer...)
+	}
+	return out
+}
+
+/**
+* Get merkle path to a leaf hash
+* @param index Leaf index to generate path for
+ */
+func (it IndexedMerkleTree) Path(index int) (ProofPath, error) {
+	return it.tree.Path(index)
+}
+
+// Leaves returns the preimages of the stored leaf hashes
+func (it IndexedMerkleTree) Leaves() []IndexedLeaf {
+	return append([]IndexedLeaf{}, it.leaves...)
+}
+
+// search returns the position in sorted of the first leaf whose value is >= value
+func (it IndexedMerkleTree) search(value *big.Int) int {
+	return sort.Search(len(it.sorted), func(i int) bool {
+		return it.leaves[it.sorted[i]].Value.BigInt().Cmp(value) >= 0
+	})
+}
+
+/**
+* Find the index of a value
+* @returns leaf index, or -1 if the value is not in the tree
+ */
+func (it IndexedMerkleTree) IndexOfValue(value Element) int {
+	v := value.BigInt()
+	pos := it.search(v)
+	if pos < len(it.sorted) && it.leaves[it.sorted[pos]].Value.BigInt().Cmp(v) == 0 {
+		return it.sorted[pos]
+	}
+	return -1
+}
+
+/**
+* Find the low leaf of a value, the leaf with the largest value below it
+* @returns leaf index and leaf
+ */
+func (it IndexedMerkleTree) LowLeaf(value Element) (int, IndexedLeaf, error) {
+	v := value.BigInt()
+	if v.Sign() <= 0 {
+		return -1, IndexedLeaf{}, fmt.Errorf("value must be positive")
+	}
+	index := it.sorted[it.search(v)-1]
+	return index, it.leaves[index], nil
+}
+
+/**
+* Insert a value
+* @param value Value to insert, must be positive and not already in the tree
+ */
+func (it *IndexedMerkleTree) Insert(value Element) error {
+	_, err := it.insert(value)
+	return err
+}
+
+/**
+* Insert several values and return one witness per insertion
+* The batch is validated up front, the tree is left unchanged if any value is rejected.
+* Every value is hashed into a leaf once first, so a value the leaf hash function rejects fails the whole batch
+ */
+func (it *IndexedMerkleTree) BatchInsert(values []Element) ([]IndexedInsertionWitness, error) {
+	if len(it.leaves)+len(values) > it.tree.Capacity() {
+		return nil, fmt.Errorf("tree is full")
+	}
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		v := value.BigInt()
+		if v.Sign() <= 0 {
+			return nil, fmt.Errorf("value must be positive")
+		}
+		if seen[v.String()] || it.IndexOfValue(value) >= 0 {
+			return nil, fmt.Errorf("value already exists: %s", value.Hex())
+		}
+		seen[v.String()] = true
+		if _, err := (IndexedLeaf{Value: value, NextValue: Element{0}}).hash(it.leafHashFn); err != nil {
+			return nil, err
+		}
+	}
+	witnesses := make([]IndexedInsertionWitness, 0, len(values))
+	for _, value := range values {
+		witness, err := it.insert(value)
+		if err != nil {
+			return nil, err
+		}
+		witnesses = append(witnesses, witness)
+	}
+	return witnesses, nil
+}
+
+func (it *IndexedMerkleTree) insert(value Element) (IndexedInsertionWitness, error) {
+	if len(it.leaves) >= it.tree.Capacity() {
+		return IndexedInsertionWitness{}, fmt.Errorf("tree is full")
+	}
+	if it.IndexOfValue(value) >= 0 {
+		return IndexedInsertionWitness{}, fmt.Errorf("value already exists: %s", value.Hex())
+	}
+	lowIndex, lowLeaf, err := it.LowLeaf(value)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	lowPath, err := it.tree.Path(lowIndex)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+
+	newIndex := len(it.leaves)
+	newLeaf := IndexedLeaf{Value: value, NextIndex: lowLeaf.NextIndex, NextValue: lowLeaf.NextValue}
+	updated := IndexedLeaf{Value: lowLeaf.Value, NextIndex: newIndex, NextValue: value}
+	// both leaves are hashed before the tree is changed, so a rejected value leaves the linked list intact
+	newHash, err := newLeaf.hash(it.leafHashFn)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	updatedHash, err := updated.hash(it.leafHashFn)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	lowHash, err := it.tree.Node(0, lowIndex)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	if err := it.tree.Update(lowIndex, updatedHash); err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	if err := it.tree.Insert(newHash); err != nil {
+		if restoreErr := it.tree.Update(lowIndex, lowHash); restoreErr != nil {
+			return IndexedInsertionWitness{}, fmt.Errorf("%w, restoring the low leaf failed: %v", err, restoreErr)
+		}
+		return IndexedInsertionWitness{}, err
+	}
+	it.leaves[lowIndex] = updated
+	it.leaves = append(it.leaves, newLeaf)
+	pos := it.search(value.BigInt())
+	it.sorted = append(it.sorted, 0)
+	copy(it.sorted[pos+1:], it.sorted[pos:])
+	it.sorted[pos] = newIndex
+
+	newPath, err := it.tree.Path(newIndex)
+	if err != nil {
+		return IndexedInsertionWitness{}, err
+	}
+	return IndexedInsertionWitness{
+		Value:        value,
+		LowLeaf:      lowLeaf,
+		LowLeafIndex: lowIndex,
+		LowLeafPath:  lowPath,
+		NewLeaf:      newLeaf,
+		NewLeafIndex: newIndex,
+		NewLeafPath:  newPath,
+	}, nil
+}
+
+/**
+* Get a membership proof for a value
+ */
+func (it IndexedMerkleTree) MembershipProof(value Element) (IndexedMembershipProof, error) {
+	index := it.IndexOfValue(value)
+	if index < 0 {
+		return IndexedMembershipProof{}, fmt.Errorf("value not found: %s", value.Hex())
+	}
+	path, err := it.Path(index)
+	if err != nil {
+		return IndexedMembershipProof{}, err
+	}
+	return IndexedMembershipProof{Leaf: it.leaves[index], Index: index, Path: path}, nil
+}
+
+/**
+* Get a non-membership proof for a value, the inclusion proof of its low leaf
+ */
+func (it IndexedMerkleTree) NonMembershipProof(value Element) (IndexedMembershipProof, error) {
+	if it.IndexOfValue(value) >= 0 {
+		return IndexedMembershipProof{}, fmt.Errorf("value exists: %s", value.Hex())
+	}
+	index, leaf, err := it.LowLeaf(value)
+	if err != nil {
+		return IndexedMembershipProof{}, err
+	}
+	path, err := it.Path(index)
+	if err != nil {
+		return IndexedMembershipProof{}, err
+	}
+	return IndexedMembershipProof{Leaf: leaf, Index: index, Path: path}, nil
+}
+
+func (p IndexedMembershipProof) verifyPath(root Element, hashFn HashFunction, leafHashFn NaryHashFunction) error {
+	if p.Index < 0 || p.Index >= 1<<len(p.Path.PathIndices) {
+		return fmt.Errorf("invalid proof: index out of bounds")
+	}
+	for level, bit := range p.Path.PathIndices {
+		if bit != (p.Index>>level)&1 {
+			return fmt.Errorf("invalid proof: path does not match index")
+		}
+	}
+	leaf, err := p.Leaf.hash(leafHashFn)
+	if err != nil {
+		return err
+	}
+	return p.Path.Verify(leaf, root, hashFn)
+}
+
+// VerifyIndexedMembership checks that value is stored in the tree with the given root
+func VerifyIndexedMembership(value Element, proof IndexedMembershipProof, root Element, hashFn HashFunction, leafHashFn NaryHashFunction) error {
+	if proof.Leaf.Value.BigInt().Cmp(value.BigInt()) != 0 {
+		return fmt.Errorf("invalid proof: leaf value mismatch")
+	}
+	return proof.verifyPath(root, hashFn, leafHashFn)
+}
+
+// VerifyIndexedNonMembership checks that value is absent from the tree with the given root
+func VerifyIndexedNonMembership(value Element, proof IndexedMembershipProof, root Element, hashFn HashFunction, leafHashFn NaryHashFunction) error {
+	v := value.BigInt()
+	if proof.Leaf.Value.BigInt().Cmp(v) >= 0 {
+		return fmt.Errorf("invalid proof: low leaf value is not below value")
+	}
+	if !proof.Leaf.isLast() && proof.Leaf.NextValue.BigInt().Cmp(v) <= 0 {
+		return fmt.Errorf("invalid proof: next value is not above value")
+	}
+	return proof.verifyPath(root, hashFn, leafHashFn)
+}
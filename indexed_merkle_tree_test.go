@@ -0,0 +1,128 @@
+package fMerkleTree
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IndexedMerkleTree(t *testing.T) {
+	t.Run("should keep leaves linked in value order", func(t *testing.T) {
+		tree, err := NewIndexedMerkleTree(10, Element{0}, Poseidon, PoseidonN)
+		require.NoError(t, err)
+		for _, v := range []Element{{30}, {10}, {20}} {
+			require.NoError(t, tree.Insert(v))
+		}
+		leaves := tree.Leaves()
+		require.Len(t, leaves, 4)
+		require.Equal(t, IndexedLeaf{Value: Element{0}, NextIndex: 2, NextValue: Element{10}}, leaves[0])
+		require.Equal(t, IndexedLeaf{Value: Element{30}, NextIndex: 0, NextValue: Element{0}}, leaves[1])
+		require.Equal(t, IndexedLeaf{Value: Element{10}, NextIndex: 3, NextValue: Element{20}}, leaves[2])
+		require.Equal(t, IndexedLeaf{Value: Element{20}, NextIndex: 1, NextValue: Element{30}}, leaves[3])
+
+		hashes := make([]Element, len(leaves))
+		for i, leaf := range leaves {
+			hashes[i] = leaf.Hash(PoseidonN)
+		}
+		dense, err := NewMerkleTree(10, hashes, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, dense.Root(), tree.Root())
+
+		require.Error(t, tree.Insert(Element{20}))
+		require.Error(t, tree.Insert(Element{0}))
+	})
+
+	t.Run("should prove membership and non-membership", func(t *testing.T) {
+		tree, err := NewIndexedMerkleTree(10, Element{0}, Poseidon2, Poseidon2N)
+		require.NoError(t, err)
+		for _, v := range []Element{{30}, {10}, {20}} {
+			require.NoError(t, tree.Insert(v))
+		}
+		root := tree.Root()
+
+		proof, err := tree.MembershipProof(Element{20})
+		require.NoError(t, err)
+		require.NoError(t, VerifyIndexedMembership(Element{20}, proof, root, Poseidon2, Poseidon2N))
+		require.Error(t, VerifyIndexedMembership(Element{21}, proof, root, Poseidon2, Poseidon2N))
+
+		for _, v := range []Element{{5}, {15}, {25}, {200}} {
+			proof, err := tree.NonMembershipProof(v)
+			require.NoError(t, err)
+			require.NoError(t, VerifyIndexedNonMembership(v, proof, root, Poseidon2, Poseidon2N))
+		}
+		proof, err = tree.NonMembershipProof(Element{15})
+		require.NoError(t, err)
+		require.Error(t, VerifyIndexedNonMembership(Element{20}, proof, root, Poseidon2, Poseidon2N))
+		_, err = tree.NonMembershipProof(Element{10})
+		require.Error(t, err)
+	})
+
+	t.Run("should return insertion witnesses", func(t *testing.T) {
+		tree, err := NewIndexedMerkleTree(10, Element{0}, Poseidon, PoseidonN)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{50}))
+		before := tree.Root()
+
+		_, err = tree.BatchInsert([]Element{{40}, {50}})
+		require.Error(t, err)
+		_, err = tree.BatchInsert([]Element{{40}, {40}})
+		require.Error(t, err)
+		require.Equal(t, before, tree.Root())
+
+		witnesses, err := tree.BatchInsert([]Element{{40}, {60}, {45}})
+		require.NoError(t, err)
+		require.Len(t, witnesses, 3)
+		root := before
+		for _, w := range witnesses {
+			require.NoError(t, w.LowLeafPath.Verify(w.LowLeaf.Hash(PoseidonN), root, Poseidon))
+			require.Equal(t, root, w.LowLeafPath.PathRoot)
+			require.Equal(t, w.LowLeaf.NextIndex, w.NewLeaf.NextIndex)
+			require.NoError(t, w.NewLeafPath.Verify(w.NewLeaf.Hash(PoseidonN), w.NewLeafPath.PathRoot, Poseidon))
+			root = w.NewLeafPath.PathRoot
+		}
+		require.Equal(t, tree.Root(), root)
+		require.Equal(t, 4, witnesses[2].NewLeafIndex)
+		require.Equal(t, 2, witnesses[2].LowLeafIndex)
+	})
+
+	t.Run("should reject values outside the field", func(t *testing.T) {
+		tree, err := NewIndexedMerkleTree(10, Element{0}, Poseidon, PoseidonN)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{50}))
+		root, leaves := tree.Root(), tree.Leaves()
+		outside := Element(new(big.Int).Add(BN254ScalarField, big.NewInt(5)).Bytes())
+
+		require.Error(t, tree.Insert(outside))
+		_, err = tree.BatchInsert([]Element{{70}, outside})
+		require.Error(t, err)
+		require.Equal(t, root, tree.Root())
+		require.Equal(t, leaves, tree.Leaves())
+
+		proof, err := tree.MembershipProof(Element{50})
+		require.NoError(t, err)
+		proof.Leaf.Value = outside
+		require.Error(t, VerifyIndexedMembership(outside, proof, root, Poseidon, PoseidonN))
+		proof, err = tree.NonMembershipProof(Element{60})
+		require.NoError(t, err)
+		proof.Leaf.NextValue = outside
+		require.Error(t, VerifyIndexedNonMembership(Element{60}, proof, root, Poseidon, PoseidonN))
+	})
+
+	t.Run("should keep the linked list when the low leaf cannot be hashed", func(t *testing.T) {
+		leafHashFn := func(inputs []Element) []byte {
+			if inputs[2].Cmp(Element{77}) {
+				panic("cannot hash")
+			}
+			return PoseidonN(inputs)
+		}
+		tree, err := NewIndexedMerkleTree(10, Element{0}, Poseidon, leafHashFn)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{50}))
+		root, leaves := tree.Root(), tree.Leaves()
+		require.ErrorContains(t, tree.Insert(Element{77}), "cannot hash")
+		require.Equal(t, root, tree.Root())
+		require.Equal(t, leaves, tree.Leaves())
+		require.NoError(t, tree.Insert(Element{60}))
+	})
+}
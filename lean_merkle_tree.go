@@ -0,0 +1,240 @@
+package fMerkleTree
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+/**
+* Dynamic depth merkle tree compatible with zk-kit's LeanIMT.
+* The depth grows as leaves are appended. A node without a right sibling is carried up
+* unchanged instead of being hashed with a zero subtree.
+ */
+type LeanMerkleTree struct {
+	hashFn HashFunction
+	layers [][]Element
+}
+
+/**
+* Merkle proof of a LeanMerkleTree leaf.
+* Levels where the node had no sibling are left out, so len(Siblings) is the depth of the leaf
+* and bit i of Index is the side of the node at the i-th recorded level.
+ */
+type LeanProof struct {
+	Root     Element   `json:"root"`
+	Leaf     Element   `json:"leaf"`
+	Index    int       `json:"index"`
+	Siblings []Element `json:"siblings"`
+}
+
+func NewLeanMerkleTree(elements []Element, hashFn HashFunction) (*LeanMerkleTree, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	out := &LeanMerkleTree{hashFn: hashFn, layers: [][]Element{{}}}
+	if err := out.BulkInsert(elements); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (lt LeanMerkleTree) Depth() int {
+	return len(lt.layers) - 1
+}
+
+func (lt LeanMerkleTree) Size() int {
+	return len(lt.layers[0])
+}
+
+func (lt LeanMerkleTree) Layers() [][]Element {
+	return lt.layers
+}
+
+func (lt LeanMerkleTree) Elements() []Element {
+	return lt.layers[0]
+}
+
+// Root returns nil for an empty tree
+func (lt LeanMerkleTree) Root() Element {
+	top := lt.layers[lt.Depth()]
+	if len(top) == 0 {
+		return nil
+	}
+	return top[0]
+}
+
+func (lt LeanMerkleTree) IndexOf(element Element) int {
+	return IndexOfElement(lt.layers[0], element, 0, nil)
+}
+
+/**
+* Insert new element into the tree, adding a level when the tree is full
+* @param element Element to insert
+ */
+func (lt *LeanMerkleTree) Insert(element Element) error {
+	size := lt.Size()
+	if lt.Depth() < bits.Len(uint(size)) {
+		lt.layers = append(lt.layers, []Element{})
+	}
+	node := element
+	index := size
+	for level := 0; level < lt.Depth(); level++ {
+		lt.setNode(level, index, node)
+		if index%2 == 1 {
+			node = lt.hashFn(lt.layers[level][index-1], node)
+		}
+		index >>= 1
+	}
+	lt.setNode(lt.Depth(), 0, node)
+	return nil
+}
+
+/**
+* Insert multiple elements into the tree.
+* @param elements Elements to insert
+ */
+func (lt *LeanMerkleTree) BulkInsert(elements []Element) error {
+	for _, element := range elements {
+		if err := lt.Insert(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+* Change an element in the tree
+* @param index Index of element to change
+* @param element Updated element value
+ */
+func (lt *LeanMerkleTree) Update(index int, element Element) error {
+	if index < 0 || index >= lt.Size() {
+		return fmt.Errorf("index out of bounds: %d", index)
+	}
+	node := element
+	for level := 0; level < lt.Depth(); level++ {
+		lt.layers[level][index] = node
+		if index%2 == 1 {
+			node = lt.hashFn(lt.layers[level][index-1], node)
+		} else if index+1 < len(lt.layers[level]) {
+			node = lt.hashFn(node, lt.layers[level][index+1])
+		}
+		index >>= 1
+	}
+	lt.layers[lt.Depth()][0] = node
+	return nil
+}
+
+func (lt *LeanMerkleTree) setNode(level, index int, node Element) {
+	if index < len(lt.layers[level]) {
+		lt.layers[level][index] = node
+	} else {
+		lt.layers[level] = append(lt.layers[level], node)
+	}
+}
+
+/**
+* Get merkle proof of a leaf
+* @param index Leaf index to generate the proof for
+ */
+func (lt LeanMerkleTree) Proof(index int) (LeanProof, error) {
+	if index < 0 || index >= lt.Size() {
+		return LeanProof{}, fmt.Errorf("index out of bounds: %d", index)
+	}
+	proof := LeanProof{Root: lt.Root(), Leaf: lt.layers[0][index], Siblings: []Element{}}
+	for level := 0; level < lt.Depth(); level++ {
+		sibling := index ^ 1
+		if sibling < len(lt.layers[level]) {
+			proof.Index |= (index % 2) << len(proof.Siblings)
+			proof.Siblings = append(proof.Siblings, lt.layers[level][sibling])
+		}
+		index >>= 1
+	}
+	return proof, nil
+}
+
+/**
+* Verify a LeanMerkleTree proof against its root
+* @param hashFn Hash function the tree was built with
+ */
+func (p LeanProof) Verify(hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	node := p.Leaf
+	for i, sibling := range p.Siblings {
+		if (p.Index>>i)%2 == 1 {
+			node = hashFn(sibling, node)
+		} else {
+			node = hashFn(node, sibling)
+		}
+	}
+	if !node.Cmp(p.Root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
+
+/**
+* Export the nodes in the JSON format of LeanIMT.export(): one array of decimal strings per level
+ */
+func (lt LeanMerkleTree) Export() ([]byte, error) {
+	nodes := make([][]string, len(lt.layers))
+	for level, layer := range lt.layers {
+		nodes[level] = make([]string, len(layer))
+		for i, node := range layer {
+			nodes[level][i] = node.BigInt().String()
+		}
+	}
+	return json.Marshal(nodes)
+}
+
+/**
+* Import nodes exported by LeanIMT.export() or LeanMerkleTree.Export
+* The tree is rebuilt from the leaves and the result is checked against the imported nodes
+* @param data JSON array of levels of decimal strings
+* @param hashFn Hash function the tree was built with
+ */
+func ImportLeanMerkleTree(data []byte, hashFn HashFunction) (*LeanMerkleTree, error) {
+	var nodes [][]string
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no levels")
+	}
+	layers := make([][]Element, len(nodes))
+	for level, layer := range nodes {
+		layers[level] = make([]Element, len(layer))
+		for i, node := range layer {
+			value, ok := new(big.Int).SetString(node, 10)
+			if !ok || value.Sign() < 0 {
+				return nil, fmt.Errorf("invalid node at level %d: %q", level, node)
+			}
+			layers[level][i] = value.Bytes()
+			if value.Sign() == 0 {
+				layers[level][i] = Element{0}
+			}
+		}
+	}
+	out, err := NewLeanMerkleTree(layers[0], hashFn)
+	if err != nil {
+		return nil, err
+	}
+	if out.Depth() != len(layers)-1 {
+		return nil, fmt.Errorf("depth mismatch")
+	}
+	for level := range layers {
+		if len(layers[level]) != len(out.layers[level]) {
+			return nil, fmt.Errorf("node count mismatch at level %d", level)
+		}
+		for i := range layers[level] {
+			if out.layers[level][i].BigInt().Cmp(layers[level][i].BigInt()) != 0 {
+				return nil, fmt.Errorf("node mismatch at level %d index %d", level, i)
+			}
+		}
+	}
+	return out, nil
+}
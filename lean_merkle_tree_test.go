@@ -0,0 +1,121 @@
+package fMerkleTree
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LeanMerkleTree(t *testing.T) {
+	t.Run("should grow without zero padding", func(t *testing.T) {
+		tree, err := NewLeanMerkleTree(nil, Poseidon)
+		require.NoError(t, err)
+		require.Nil(t, tree.Root())
+		require.NoError(t, tree.Insert(Element{1}))
+		require.Equal(t, 0, tree.Depth())
+		require.Equal(t, Element{1}, tree.Root())
+		require.NoError(t, tree.BulkInsert([]Element{{2}, {3}}))
+		require.Equal(t, 2, tree.Depth())
+		require.Equal(t, Element(Poseidon(Poseidon(Element{1}, Element{2}), Element{3})), tree.Root())
+		require.NoError(t, tree.Insert(Element{4}))
+		require.NoError(t, tree.Insert(Element{5}))
+		require.Equal(t, 3, tree.Depth())
+
+		fixed, err := NewMerkleTree(2, []Element{{1}, {2}, {3}, {4}}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, Element(Poseidon(fixed.Root(), Element{5})), tree.Root())
+
+		require.NoError(t, tree.Update(4, Element{6}))
+		require.Equal(t, Element(Poseidon(fixed.Root(), Element{6})), tree.Root())
+		require.Error(t, tree.Update(5, Element{7}))
+	})
+
+	t.Run("should record the actual depth in proofs", func(t *testing.T) {
+		tree, err := NewLeanMerkleTree(newTestElements(0, 5), Poseidon)
+		require.NoError(t, err)
+		proof, err := tree.Proof(4)
+		require.NoError(t, err)
+		require.Len(t, proof.Siblings, 1)
+		require.Equal(t, 1, proof.Index)
+		require.NoError(t, proof.Verify(Poseidon))
+
+		for i := 0; i < tree.Size(); i++ {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			require.NoError(t, proof.Verify(Poseidon))
+			proof.Leaf = Element{42}
+			require.Error(t, proof.Verify(Poseidon))
+		}
+		proof, err = tree.Proof(2)
+		require.NoError(t, err)
+		require.Len(t, proof.Siblings, 3)
+		require.Equal(t, 0b010, proof.Index)
+	})
+
+	t.Run("should import and export the LeanIMT format", func(t *testing.T) {
+		tree, err := NewLeanMerkleTree(newTestElements(0, 3), Poseidon)
+		require.NoError(t, err)
+		data, err := tree.Export()
+		require.NoError(t, err)
+		var nodes [][]string
+		require.NoError(t, json.Unmarshal(data, &nodes))
+		require.Equal(t, []string{"1", "2", "3"}, nodes[0])
+		require.Equal(t, "3", nodes[1][1])
+		require.Equal(t, tree.Root().BigInt().String(), nodes[2][0])
+
+		imported, err := ImportLeanMerkleTree(data, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), imported.Root())
+
+		nodes[1][0] = "42"
+		tampered, err := json.Marshal(nodes)
+		require.NoError(t, err)
+		_, err = ImportLeanMerkleTree(tampered, Poseidon)
+		require.Error(t, err)
+		_, err = ImportLeanMerkleTree([]byte(`[["1","x"]]`), Poseidon)
+		require.Error(t, err)
+	})
+
+	t.Run("should match zk-kit LeanIMT", func(t *testing.T) {
+		// written by testdata/lean_imt/generate.mjs with @zk-kit/lean-imt and poseidon-lite
+		exported, err := os.ReadFile("testdata/lean_imt/export.json")
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Skip("zk-kit fixture missing, run testdata/lean_imt/generate.mjs")
+		}
+		require.NoError(t, err)
+		data, err := os.ReadFile("testdata/lean_imt/proofs.json")
+		require.NoError(t, err)
+		var proofs []struct {
+			Root     string   `json:"root"`
+			Leaf     string   `json:"leaf"`
+			Index    int      `json:"index"`
+			Siblings []string `json:"siblings"`
+		}
+		require.NoError(t, json.Unmarshal(data, &proofs))
+
+		tree, err := ImportLeanMerkleTree(exported, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, len(proofs), tree.Size())
+		data, err = tree.Export()
+		require.NoError(t, err)
+		require.JSONEq(t, string(exported), string(data))
+
+		for i, expected := range proofs {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			require.Equal(t, expected.Root, proof.Root.BigInt().String())
+			require.Equal(t, expected.Leaf, proof.Leaf.BigInt().String())
+			require.Equal(t, expected.Index, proof.Index)
+			siblings := make([]string, len(proof.Siblings))
+			for j, sibling := range proof.Siblings {
+				siblings[j] = sibling.BigInt().String()
+			}
+			require.Equal(t, expected.Siblings, siblings)
+			require.NoError(t, proof.Verify(Poseidon))
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+/**
+* Merkle Mountain Range, an append-only accumulator without a fixed capacity.
+* layers[h] holds every complete node of height h from left to right, so the leaves
+* are layers[0] and the peaks are the last nodes of the heights set in the size.
+ */
+type MerkleMountainRange struct {
+	hashFn HashFunction
+	layers [][]Element
+}
+
+/**
+* Inclusion proof of a leaf in a Merkle Mountain Range of Size leaves.
+* Siblings lead from the leaf to its peak, Peaks are all peaks from left to right.
+ */
+type MMRProof struct {
+	LeafIndex int       `json:"leafIndex"`
+	Size      int       `json:"size"`
+	Siblings  []Element `json:"siblings"`
+	Peaks     []Element `json:"peaks"`
+}
+
+func NewMerkleMountainRange(elements []Element, hashFn HashFunction) (*MerkleMountainRange, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	out := &MerkleMountainRange{hashFn: hashFn, layers: [][]Element{{}}}
+	for _, element := range elements {
+		out.Append(element)
+	}
+	return out, nil
+}
+
+// NewMerkleMountainRangeFrom appends the leaves of a fixed tree to an empty range
+func NewMerkleMountainRangeFrom(mt *MerkleTree) (*MerkleMountainRange, error) {
+	return NewMerkleMountainRange(mt.detachAll(mt.layer(0)), mt.hashFn)
+}
+
+func (m MerkleMountainRange) Size() int {
+	return len(m.layers[0])
+}
+
+func (m MerkleMountainRange) Elements() []Element {
+	return m.layers[0]
+}
+
+/**
+* Append new element
+* @param element Element to append
+* @returns index of the appended element
+ */
+func (m *MerkleMountainRange) Append(element Element) int {
+	m.layers[0] = append(m.layers[0], element)
+	for height := 0; len(m.layers[height])%2 == 0; height++ {
+		if height+1 == len(m.layers) {
+			m.layers = append(m.layers, []Element{})
+		}
+		nodes := m.layers[height]
+		m.layers[height+1] = append(m.layers[height+1], m.hashFn(nodes[len(nodes)-2], nodes[len(nodes)-1]))
+	}
+	return len(m.layers[0]) - 1
+}
+
+// Peaks returns the roots of the perfect subtrees, highest first
+func (m MerkleMountainRange) Peaks() []Element {
+	size := m.Size()
+	peaks := make([]Element, 0, bits.OnesCount(uint(size)))
+	for height := len(m.layers) - 1; height >= 0; height-- {
+		if (size>>height)%2 == 1 {
+			peaks = append(peaks, m.layers[height][(size>>height)-1])
+		}
+	}
+	return peaks
+}
+
+/**
+* Bag the peaks from right to left into a single root
+* @returns nil for an empty range
+ */
+func (m MerkleMountainRange) Root() Element {
+	return bagPeaks(m.Peaks(), m.hashFn)
+}
+
+func bagPeaks(peaks []Element, hashFn HashFunction) Element {
+	if len(peaks) == 0 {
+		return nil
+	}
+	root := peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = hashFn(peaks[i], root)
+	}
+	return root
+}
+
+// locatePeak returns the height of the peak holding index, its position among the peaks and its first leaf
+func locatePeak(index, size int) (height, peak, start int) {
+	for height = bits.Len(uint(size)) - 1; height >= 0; height-- {
+		if (size>>height)%2 == 0 {
+			continue
+		}
+		if index < start+1<<height {
+			return height, peak, start
+		}
+		start += 1 << height
+		peak++
+	}
+	return -1, -1, -1
+}
+
+/**
+* Get an inclusion proof of a leaf
+* @param index Leaf index to generate the proof for
+ */
+func (m MerkleMountainRange) Proof(index int) (MMRProof, error) {
+	size := m.Size()
+	if index < 0 || index >= size {
+		return MMRProof{}, fmt.Errorf("index out of bounds: %d", index)
+	}
+	height, _, _ := locatePeak(index, size)
+	proof := MMRProof{
+		LeafIndex: index,
+		Size:      size,
+		Siblings:  make([]Element, height),
+		Peaks:     m.Peaks(),
+	}
+	for level := 0; level < height; level++ {
+		proof.Siblings[level] = m.layers[level][(index>>level)^1]
+	}
+	return proof, nil
+}
+
+/**
+* Verify an inclusion proof against a trusted bagged root
+* @param leaf Leaf value the proof was generated for
+* @param root Trusted root of the range
+* @param hashFn Hash function the range was built with
+ */
+func (p MMRProof) Verify(leaf Element, root Element, hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	if p.LeafIndex < 0 || p.LeafIndex >= p.Size {
+		return fmt.Errorf("invalid proof: index out of bounds")
+	}
+	height, peak, start := locatePeak(p.LeafIndex, p.Size)
+	if len(p.Siblings) != height || len(p.Peaks) != bits.OnesCount(uint(p.Size)) {
+		return fmt.Errorf("invalid proof: shape does not match size %d", p.Size)
+	}
+	node := leaf
+	local := p.LeafIndex - start
+	for level, sibling := range p.Siblings {
+		if (local>>level)%2 == 0 {
+			node = hashFn(node, sibling)
+		} else {
+			node = hashFn(sibling, node)
+		}
+	}
+	if !node.Cmp(p.Peaks[peak]) {
+		return fmt.Errorf("invalid proof: peak mismatch")
+	}
+	if !bagPeaks(p.Peaks, hashFn).Cmp(root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
+
+func VerifyMMRProof(leaf Element, proof MMRProof, root Element, hashFn HashFunction) error {
+	return proof.Verify(leaf, root, hashFn)
+}
+
+/**
+* Root of a fixed depth tree holding the same leaves, computed from the peaks only.
+* Peak h of the range is the node layers[h][size>>h-1] of the fixed tree, the fixed root
+* folds the peaks with the zero subtrees on the right.
+* @param levels Levels of the fixed tree
+* @param zeroElement Zero element of the fixed tree
+ */
+func (m MerkleMountainRange) FixedRoot(levels int, zeroElement Element) (Element, error) {
+	size := m.Size()
+	if levels < 0 || levels >= 63 || size > 1<<levels {
+		return nil, fmt.Errorf("tree is full")
+	}
+	if size == 1<<levels {
+		return m.layers[levels][0], nil
+	}
+	zeros := buildZeros(levels, zeroElement, m.hashFn)
+	node := zeros[0]
+	for level := 0; level < levels; level++ {
+		if (size>>level)%2 == 1 {
+			node = m.hashFn(m.layers[level][(size>>level)-1], node)
+		} else {
+			node = m.hashFn(node, zeros[level])
+		}
+	}
+	return node, nil
+}
+
+// ToMerkleTree builds a fixed depth tree holding the same leaves
+func (m MerkleMountainRange) ToMerkleTree(levels int, zeroElement Element, opts ...TreeOption) (*MerkleTree, error) {
+	return NewMerkleTree(levels, append([]Element{}, m.layers[0]...), zeroElement, m.hashFn, opts...)
+}
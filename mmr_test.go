@@ -0,0 +1,68 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MerkleMountainRange(t *testing.T) {
+	t.Run("should track peaks while appending", func(t *testing.T) {
+		mmr, err := NewMerkleMountainRange(nil, SHA256Hash)
+		require.NoError(t, err)
+		require.Nil(t, mmr.Root())
+		for i := 0; i < 11; i++ {
+			require.Equal(t, i, mmr.Append(Element{byte(i + 1)}))
+		}
+		peaks := mmr.Peaks()
+		require.Len(t, peaks, 3)
+
+		// 11 leaves: perfect subtrees of 8, 2 and 1 leaves
+		eight, err := NewMerkleTree(3, newTestElements(0, 8), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, eight.Root(), peaks[0])
+		require.Equal(t, Element(SHA256Hash(Element{9}, Element{10})), peaks[1])
+		require.Equal(t, Element{11}, peaks[2])
+		require.Equal(t, Element(SHA256Hash(peaks[0], SHA256Hash(peaks[1], peaks[2]))), mmr.Root())
+	})
+
+	t.Run("should prove every leaf", func(t *testing.T) {
+		mmr, err := NewMerkleMountainRange(newTestElements(0, 13), Poseidon)
+		require.NoError(t, err)
+		for i, leaf := range mmr.Elements() {
+			proof, err := mmr.Proof(i)
+			require.NoError(t, err)
+			require.NoError(t, VerifyMMRProof(leaf, proof, mmr.Root(), Poseidon))
+			require.Error(t, VerifyMMRProof(Element{99}, proof, mmr.Root(), Poseidon))
+		}
+		_, err = mmr.Proof(13)
+		require.Error(t, err)
+
+		proof, err := mmr.Proof(4)
+		require.NoError(t, err)
+		proof.Size = 12
+		require.Error(t, proof.Verify(Element{5}, mmr.Root(), Poseidon))
+	})
+
+	t.Run("should relate to the fixed tree holding the same leaves", func(t *testing.T) {
+		tree, err := NewMerkleTree(5, []Element{}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		mmr, err := NewMerkleMountainRangeFrom(tree)
+		require.NoError(t, err)
+		for i := 0; i < 32; i++ {
+			require.NoError(t, tree.Insert(Element{byte(i + 1)}))
+			mmr.Append(Element{byte(i + 1)})
+			root, err := mmr.FixedRoot(5, Element{0})
+			require.NoError(t, err)
+			require.Equal(t, tree.Root(), root)
+		}
+		mmr.Append(Element{33})
+		_, err = mmr.FixedRoot(5, Element{0})
+		require.Error(t, err)
+		fixed, err := mmr.ToMerkleTree(6, Element{0})
+		require.NoError(t, err)
+		root, err := mmr.FixedRoot(6, Element{0})
+		require.NoError(t, err)
+		require.Equal(t, fixed.Root(), root)
+	})
+}
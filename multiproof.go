@@ -0,0 +1,186 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"sort"
+)
+
+/**
+* Proof of several leaves against a single root.
+* Siblings are listed once, in the order the verifier consumes them (level by level, left to right).
+* Flags has one entry per hash computed by the verifier: true when both children are already known,
+* false when the missing child is taken from Siblings.
+ */
+type MultiProof struct {
+	LeafIndices []int     `json:"leafIndices"`
+	Siblings    []Element `json:"siblings"`
+	Flags       []bool    `json:"flags"`
+	Levels      int       `json:"levels"`
+	Root        Element   `json:"root"`
+}
+
+/**
+* Get a multiproof for a set of leaves
+* @param indices Leaf indices to prove, duplicates are ignored
+* @returns MultiProof with LeafIndices sorted in ascending order
+ */
+func (bt *BaseTree) MultiProof(indices []int) (MultiProof, error) {
+	if len(indices) == 0 {
+		return MultiProof{}, fmt.Errorf("no indices to prove")
+	}
+	if err := bt.Commit(); err != nil {
+		return MultiProof{}, err
+	}
+	known := make([]int, 0, len(indices))
+	seen := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= bt.layerLen(0) {
+			return MultiProof{}, fmt.Errorf("index out of bounds: %d", index)
+		}
+		if !seen[index] {
+			seen[index] = true
+			known = append(known, index)
+		}
+	}
+	sort.Ints(known)
+
+	proof := MultiProof{
+		LeafIndices: append([]int(nil), known...),
+		Levels:      bt.levels,
+		Root:        bt.Root(),
+	}
+	for level := 0; level < bt.levels; level++ {
+		parents := make([]int, 0, len(known))
+		for i := 0; i < len(known); i++ {
+			index := known[i]
+			if index%2 == 0 && i+1 < len(known) && known[i+1] == index+1 {
+				proof.Flags = append(proof.Flags, true)
+				i++
+			} else {
+				sibling := index ^ 1
+				if sibling < bt.layerLen(level) {
+					proof.Siblings = append(proof.Siblings, bt.detach(bt.at(level, sibling)))
+				} else {
+					proof.Siblings = append(proof.Siblings, bt.zeros[level])
+				}
+				proof.Flags = append(proof.Flags, false)
+			}
+			parents = append(parents, index>>1)
+		}
+		known = parents
+	}
+	return proof, nil
+}
+
+/**
+* Compute the root implied by a multiproof
+* A panic inside hashFn is returned as an error
+* @param leaves Leaf values in the order of LeafIndices
+* @param hashFn Hash function the tree was built with
+ */
+func (p MultiProof) ComputeRoot(leaves []Element, hashFn HashFunction) (Element, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	return p.ComputeRootWithHasher(leaves, hasherOf(hashFn))
+}
+
+// ComputeRootWithHasher is ComputeRoot returning the errors of hasher
+func (p MultiProof) ComputeRootWithHasher(leaves []Element, hasher Hasher) (Element, error) {
+	if hasher == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	if len(p.LeafIndices) == 0 {
+		return nil, fmt.Errorf("invalid proof: no leaves")
+	}
+	if len(leaves) != len(p.LeafIndices) {
+		return nil, fmt.Errorf("invalid proof: %d leaves, %d indices", len(leaves), len(p.LeafIndices))
+	}
+	if p.Levels < 0 || p.Levels >= 63 {
+		return nil, fmt.Errorf("invalid proof: levels %d", p.Levels)
+	}
+	for i, index := range p.LeafIndices {
+		if index < 0 || index >= 1<<p.Levels || (i > 0 && index <= p.LeafIndices[i-1]) {
+			return nil, fmt.Errorf("invalid proof: leaf indices must be unique, sorted and within capacity")
+		}
+	}
+
+	indices := append([]int(nil), p.LeafIndices...)
+	nodes := append([]Element(nil), leaves...)
+	siblingPos, flagPos := 0, 0
+	for level := 0; level < p.Levels; level++ {
+		nextIndices := make([]int, 0, len(indices))
+		nextNodes := make([]Element, 0, len(nodes))
+		for i := 0; i < len(indices); i++ {
+			if flagPos >= len(p.Flags) {
+				return nil, fmt.Errorf("invalid proof: not enough flags")
+			}
+			flag := p.Flags[flagPos]
+			flagPos++
+
+			index := indices[i]
+			var left, right Element
+			if index%2 == 0 && i+1 < len(indices) && indices[i+1] == index+1 {
+				if !flag {
+					return nil, fmt.Errorf("invalid proof: unexpected flag at level %d", level)
+				}
+				left, right = nodes[i], nodes[i+1]
+				i++
+			} else {
+				if flag {
+					return nil, fmt.Errorf("invalid proof: unexpected flag at level %d", level)
+				}
+				if siblingPos >= len(p.Siblings) {
+					return nil, fmt.Errorf("invalid proof: not enough siblings")
+				}
+				sibling := p.Siblings[siblingPos]
+				siblingPos++
+				if index%2 == 0 {
+					left, right = nodes[i], sibling
+				} else {
+					left, right = sibling, nodes[i]
+				}
+			}
+			node, err := hasher.Hash(left, right)
+			if err != nil {
+				return nil, err
+			}
+			nextIndices = append(nextIndices, index>>1)
+			nextNodes = append(nextNodes, node)
+		}
+		indices, nodes = nextIndices, nextNodes
+	}
+	if siblingPos != len(p.Siblings) || flagPos != len(p.Flags) {
+		return nil, fmt.Errorf("invalid proof: unused siblings or flags")
+	}
+	return nodes[0], nil
+}
+
+/**
+* Verify a multiproof against a trusted root
+* @param leaves Leaf values in the order of LeafIndices
+* @param root Trusted root
+* @param hashFn Hash function the tree was built with
+ */
+func (p MultiProof) Verify(leaves []Element, root Element, hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	return p.VerifyWithHasher(leaves, root, hasherOf(hashFn))
+}
+
+// VerifyWithHasher is Verify returning the errors of hasher
+func (p MultiProof) VerifyWithHasher(leaves []Element, root Element, hasher Hasher) error {
+	computed, err := p.ComputeRootWithHasher(leaves, hasher)
+	if err != nil {
+		return err
+	}
+	if !computed.Cmp(root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
+
+func VerifyMultiProof(leaves []Element, proof MultiProof, root Element, hashFn HashFunction) error {
+	return proof.Verify(leaves, root, hashFn)
+}
@@ -0,0 +1,115 @@
+package fMerkleTree
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MultiProof(t *testing.T) {
+	elements := []Element{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}}
+
+	t.Run("should verify against the tree root", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		proof, err := tree.MultiProof([]int{8, 0, 3, 2, 3})
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 2, 3, 8}, proof.LeafIndices)
+		leaves := []Element{{1}, {3}, {4}, {9}}
+		require.NoError(t, VerifyMultiProof(leaves, proof, tree.Root(), SHA256Hash))
+		require.Error(t, VerifyMultiProof([]Element{{1}, {3}, {5}, {9}}, proof, tree.Root(), SHA256Hash))
+	})
+
+	t.Run("should match per-leaf paths", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, elements, Element{0}, Poseidon)
+		require.NoError(t, err)
+		indices := []int{1, 4, 5, 7}
+		proof, err := tree.MultiProof(indices)
+		require.NoError(t, err)
+
+		// replay the verifier's walk and rebuild the path of every leaf from the proof
+		leaves := []Element{{2}, {5}, {6}, {8}}
+		nodes := map[int]Element{}
+		below := map[int][]int{}
+		rebuilt := map[int]*ProofPath{}
+		for i, index := range indices {
+			nodes[index] = leaves[i]
+			below[index] = []int{index}
+			rebuilt[index] = &ProofPath{PathElements: make([]Element, tree.levels), PathIndices: make([]int, tree.levels)}
+		}
+		pos := 0
+		for level := 0; level < tree.levels; level++ {
+			known := make([]int, 0, len(nodes))
+			for index := range nodes {
+				known = append(known, index)
+			}
+			sort.Ints(known)
+			nextNodes := map[int]Element{}
+			nextBelow := map[int][]int{}
+			for _, index := range known {
+				sibling, ok := nodes[index^1]
+				if !ok {
+					require.Less(t, pos, len(proof.Siblings))
+					sibling = proof.Siblings[pos]
+					pos++
+				}
+				for _, leaf := range below[index] {
+					rebuilt[leaf].PathElements[level] = sibling
+					rebuilt[leaf].PathIndices[level] = index % 2
+				}
+				if index%2 == 0 {
+					nextNodes[index>>1] = Poseidon(nodes[index], sibling)
+				} else if !ok {
+					nextNodes[index>>1] = Poseidon(sibling, nodes[index])
+				}
+				nextBelow[index>>1] = append(nextBelow[index>>1], below[index]...)
+			}
+			nodes, below = nextNodes, nextBelow
+		}
+		require.Equal(t, len(proof.Siblings), pos)
+		require.Less(t, len(proof.Siblings), len(indices)*tree.levels)
+
+		for _, i := range indices {
+			path, err := tree.Path(i)
+			require.NoError(t, err)
+			require.Equal(t, path.PathElements, rebuilt[i].PathElements)
+			require.Equal(t, path.PathIndices, rebuilt[i].PathIndices)
+			require.Equal(t, path.PathRoot, Element(nodes[0]))
+		}
+
+		root, err := proof.ComputeRoot(leaves, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), root)
+	})
+
+	t.Run("should reject tampered proofs", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		proof, err := tree.MultiProof([]int{0, 1, 6})
+		require.NoError(t, err)
+		leaves := []Element{{1}, {2}, {7}}
+
+		flipped := proof
+		flipped.Flags = append([]bool(nil), proof.Flags...)
+		flipped.Flags[0] = !flipped.Flags[0]
+		require.Error(t, flipped.Verify(leaves, tree.Root(), SHA256Hash))
+
+		short := proof
+		short.Siblings = proof.Siblings[1:]
+		require.Error(t, short.Verify(leaves, tree.Root(), SHA256Hash))
+
+		unsorted := proof
+		unsorted.LeafIndices = []int{1, 0, 6}
+		require.Error(t, unsorted.Verify(leaves, tree.Root(), SHA256Hash))
+	})
+
+	t.Run("should fail on incorrect index", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		_, err = tree.MultiProof([]int{9})
+		require.Error(t, err)
+		_, err = tree.MultiProof(nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,258 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+* Fixed depth merkle tree where every node has arity children.
+* With arity 2 and NaryFromBinary it produces the same roots as MerkleTree.
+ */
+type NaryMerkleTree struct {
+	levels      int
+	arity       int
+	hashFn      NaryHashFunction
+	zeroElement Element
+	zeros       []Element
+	layers      [][]Element
+}
+
+func NewNaryMerkleTree(levels, arity int, elements []Element, zeroElement Element, hashFn NaryHashFunction) (*NaryMerkleTree, error) {
+	if arity < 2 || arity > 16 {
+		return nil, fmt.Errorf("invalid arity: %d", arity)
+	}
+	if levels < 0 || float64(levels)*math.Log2(float64(arity)) >= 63 {
+		return nil, fmt.Errorf("invalid levels: %d", levels)
+	}
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	out := &NaryMerkleTree{
+		levels:      levels,
+		arity:       arity,
+		hashFn:      hashFn,
+		zeroElement: zeroElement,
+	}
+	if len(elements) > out.Capacity() {
+		return nil, fmt.Errorf("tree is full")
+	}
+	if err := out.buildZeros(); err != nil {
+		return nil, err
+	}
+	out.layers = make([][]Element, levels+1)
+	// the caller's slice is copied, appending to it would write into its backing array
+	out.layers[0] = append([]Element{}, elements...)
+	for level := 1; level <= levels; level++ {
+		out.layers[level] = out.processNodes(out.layers[level-1], level)
+	}
+	return out, nil
+}
+
+func (nt NaryMerkleTree) Capacity() int {
+	capacity := 1
+	for i := 0; i < nt.levels; i++ {
+		capacity *= nt.arity
+	}
+	return capacity
+}
+
+func (nt NaryMerkleTree) Arity() int {
+	return nt.arity
+}
+
+func (nt NaryMerkleTree) Layers() [][]Element {
+	return nt.layers
+}
+
+func (nt NaryMerkleTree) Zeros() []Element {
+	return nt.zeros
+}
+
+func (nt NaryMerkleTree) Elements() []Element {
+	return nt.layers[0]
+}
+
+func (nt NaryMerkleTree) Root() Element {
+	if len(nt.layers[nt.levels]) == 0 {
+		return nt.zeros[nt.levels]
+	}
+	return nt.layers[nt.levels][0]
+}
+
+// buildZeros hashes the empty subtrees, a hash function that rejects arity inputs, e.g. NaryFromBinary with an
+// arity other than 2, fails here with an error instead of panicking on the first insert
+func (nt *NaryMerkleTree) buildZeros() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hash function does not take %d inputs: %v", nt.arity, r)
+		}
+	}()
+	nt.zeros = make([]Element, nt.levels+1)
+	nt.zeros[0] = nt.zeroElement
+	for i := 1; i <= nt.levels; i++ {
+		inputs := make([]Element, nt.arity)
+		for j := range inputs {
+			inputs[j] = nt.zeros[i-1]
+		}
+		nt.zeros[i] = nt.hashFn(inputs)
+	}
+	return nil
+}
+
+// children returns the inputs of the node at (level, index), padded with zero subtrees
+func (nt NaryMerkleTree) children(nodes []Element, level, index int) []Element {
+	inputs := make([]Element, nt.arity)
+	for j := range inputs {
+		child := index*nt.arity + j
+		if child < len(nodes) {
+			inputs[j] = nodes[child]
+		} else {
+			inputs[j] = nt.zeros[level-1]
+		}
+	}
+	return inputs
+}
+
+func (nt NaryMerkleTree) processNodes(nodes []Element, level int) []Element {
+	layer := make([]Element, (len(nodes)+nt.arity-1)/nt.arity)
+	for i := range layer {
+		layer[i] = nt.hashFn(nt.children(nodes, level, i))
+	}
+	return layer
+}
+
+func (nt *NaryMerkleTree) processUpdate(index int) {
+	for level := 1; level <= nt.levels; level++ {
+		index /= nt.arity
+		node := nt.hashFn(nt.children(nt.layers[level-1], level, index))
+		if index < len(nt.layers[level]) {
+			nt.layers[level][index] = node
+		} else {
+			nt.layers[level] = append(nt.layers[level], node)
+		}
+	}
+}
+
+/**
+* Insert new element into the tree
+* @param element Element to insert
+ */
+func (nt *NaryMerkleTree) Insert(element Element) error {
+	if len(nt.layers[0]) >= nt.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	return nt.Update(len(nt.layers[0]), element)
+}
+
+/**
+* Insert multiple elements into the tree.
+* @param elements Elements to insert
+ */
+func (nt *NaryMerkleTree) BulkInsert(elements []Element) error {
+	if len(nt.layers[0])+len(elements) > nt.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	for _, element := range elements {
+		if err := nt.Insert(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+* Change an element in the tree
+* @param index Index of element to change
+* @param element Updated element value
+ */
+func (nt *NaryMerkleTree) Update(index int, element Element) error {
+	if index < 0 || index > len(nt.layers[0]) || index >= nt.Capacity() {
+		return fmt.Errorf("index out of bounds: %d", index)
+	}
+	if index == len(nt.layers[0]) {
+		nt.layers[0] = append(nt.layers[0], element)
+	} else {
+		nt.layers[0][index] = element
+	}
+	nt.processUpdate(index)
+	return nil
+}
+
+/**
+* Get merkle path to a leaf
+* @param index Leaf index to generate path for
+* @returns arity-1 siblings per level and the position of the path node among its siblings
+ */
+func (nt NaryMerkleTree) Path(index int) (NaryProofPath, error) {
+	if index < 0 || index >= len(nt.layers[0]) {
+		return NaryProofPath{}, fmt.Errorf("index out of bounds: %d", index)
+	}
+	path := NaryProofPath{
+		PathElements: make([][]Element, nt.levels),
+		PathIndices:  make([]int, nt.levels),
+		PathRoot:     nt.Root(),
+	}
+	for level := 0; level < nt.levels; level++ {
+		position := index % nt.arity
+		parent := index / nt.arity
+		children := nt.children(nt.layers[level], level+1, parent)
+		path.PathIndices[level] = position
+		path.PathElements[level] = append(children[:position:position], children[position+1:]...)
+		index = parent
+	}
+	return path, nil
+}
+
+func (nt NaryMerkleTree) IndexOf(element Element) int {
+	return IndexOfElement(nt.layers[0], element, 0, nil)
+}
+
+func (nt NaryMerkleTree) Proof(element Element) (NaryProofPath, error) {
+	return nt.Path(nt.IndexOf(element))
+}
+
+/**
+* Compute the root implied by a leaf and its n-ary merkle path
+* @param leaf Leaf value the path was generated for
+* @param hashFn Hash function the tree was built with
+ */
+func (p NaryProofPath) ComputeRoot(leaf Element, hashFn NaryHashFunction) (Element, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	if len(p.PathElements) != len(p.PathIndices) {
+		return nil, fmt.Errorf("invalid proof: %d path elements, %d path indices", len(p.PathElements), len(p.PathIndices))
+	}
+	node := leaf
+	for level, siblings := range p.PathElements {
+		position := p.PathIndices[level]
+		if position < 0 || position > len(siblings) || len(siblings) != len(p.PathElements[0]) {
+			return nil, fmt.Errorf("invalid proof: position %d at level %d", position, level)
+		}
+		inputs := make([]Element, 0, len(siblings)+1)
+		inputs = append(inputs, siblings[:position]...)
+		inputs = append(inputs, node)
+		inputs = append(inputs, siblings[position:]...)
+		node = hashFn(inputs)
+	}
+	return node, nil
+}
+
+/**
+* Verify an n-ary merkle path against a trusted root
+ */
+func (p NaryProofPath) Verify(leaf Element, root Element, hashFn NaryHashFunction) error {
+	computed, err := p.ComputeRoot(leaf, hashFn)
+	if err != nil {
+		return err
+	}
+	if !computed.Cmp(root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
+
+func VerifyNaryPath(leaf Element, proof NaryProofPath, root Element, hashFn NaryHashFunction) error {
+	return proof.Verify(leaf, root, hashFn)
+}
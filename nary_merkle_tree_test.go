@@ -0,0 +1,80 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NaryMerkleTree(t *testing.T) {
+	t.Run("should match MerkleTree with arity 2", func(t *testing.T) {
+		elements := newTestElements(0, 11)
+		tree, err := NewMerkleTree(10, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		nary, err := NewNaryMerkleTree(10, 2, elements, Element{0}, NaryFromBinary(SHA256Hash))
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), nary.Root())
+
+		require.NoError(t, tree.Insert(Element{42}))
+		require.NoError(t, nary.Insert(Element{42}))
+		require.NoError(t, tree.Update(3, Element{43}))
+		require.NoError(t, nary.Update(3, Element{43}))
+		require.Equal(t, tree.Root(), nary.Root())
+
+		path, err := tree.Path(5)
+		require.NoError(t, err)
+		naryPath, err := nary.Path(5)
+		require.NoError(t, err)
+		require.Equal(t, path.PathIndices, naryPath.PathIndices)
+		for level := range path.PathElements {
+			require.Equal(t, []Element{path.PathElements[level]}, naryPath.PathElements[level])
+		}
+		require.Panics(t, func() { NaryFromBinary(SHA256Hash)([]Element{{1}, {2}, {3}}) })
+		_, err = NewNaryMerkleTree(2, 3, elements[:3], Element{0}, NaryFromBinary(SHA256Hash))
+		require.ErrorContains(t, err, "hash function does not take 3 inputs")
+	})
+
+	t.Run("should build quinary poseidon trees", func(t *testing.T) {
+		nary, err := NewNaryMerkleTree(4, 5, []Element{}, Element{0}, PoseidonN)
+		require.NoError(t, err)
+		require.Equal(t, 625, nary.Capacity())
+		require.NoError(t, nary.BulkInsert(newTestElements(0, 27)))
+
+		built, err := NewNaryMerkleTree(4, 5, newTestElements(0, 27), Element{0}, PoseidonN)
+		require.NoError(t, err)
+		require.Equal(t, built.Root(), nary.Root())
+
+		for _, index := range []int{0, 4, 5, 13, 26} {
+			path, err := nary.Path(index)
+			require.NoError(t, err)
+			require.Len(t, path.PathElements[0], 4)
+			require.Equal(t, index%5, path.PathIndices[0])
+			require.NoError(t, VerifyNaryPath(nary.Elements()[index], path, nary.Root(), PoseidonN))
+			require.Error(t, VerifyNaryPath(Element{99}, path, nary.Root(), PoseidonN))
+		}
+		proof, err := nary.Proof(Element{14})
+		require.NoError(t, err)
+		require.Equal(t, []int{3, 2, 0, 0}, proof.PathIndices)
+	})
+
+	t.Run("should not write into the elements", func(t *testing.T) {
+		elements := newTestElements(0, 4)
+		nary, err := NewNaryMerkleTree(2, 2, elements[:3], Element{0}, NaryFromBinary(SHA256Hash))
+		require.NoError(t, err)
+		require.NoError(t, nary.Insert(Element{7}))
+		require.Equal(t, newTestElements(0, 4), elements)
+	})
+
+	t.Run("should validate parameters", func(t *testing.T) {
+		_, err := NewNaryMerkleTree(4, 17, nil, Element{0}, PoseidonN)
+		require.Error(t, err)
+		_, err = NewNaryMerkleTree(1, 4, newTestElements(0, 5), Element{0}, Poseidon2N)
+		require.Error(t, err)
+		nary, err := NewNaryMerkleTree(1, 4, newTestElements(0, 4), Element{0}, Poseidon2N)
+		require.NoError(t, err)
+		require.Error(t, nary.Insert(Element{5}))
+		require.Error(t, nary.Update(5, Element{5}))
+		_, err = nary.Path(4)
+		require.Error(t, err)
+	})
+}
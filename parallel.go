@@ -0,0 +1,85 @@
+package fMerkleTree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// minPairsPerWorker keeps small layers on one goroutine and is the chunk size between cancellation checks
+const minPairsPerWorker = 256
+
+/**
+* Hash the layers of the initial tree in parallel chunks
+* The result is identical to the sequential build. The hasher must be safe for concurrent use
+* @param workers Number of goroutines per layer
+ */
+func WithWorkers(workers int) TreeOption {
+	return func(bt *BaseTree) error {
+		if workers < 1 {
+			return fmt.Errorf("workers must be positive, got %d", workers)
+		}
+		bt.workers = workers
+		return nil
+	}
+}
+
+func (bt BaseTree) Workers() int {
+	if bt.workers < 1 {
+		return 1
+	}
+	return bt.workers
+}
+
+// hashLayer hashes nodes[2i] with nodes[2i+1], splitting the pairs between the workers of the tree
+func (bt *BaseTree) hashLayer(ctx context.Context, nodes []Element) ([]Element, error) {
+	count := len(nodes) / 2
+	workers := min(bt.Workers(), count/minPairsPerWorker)
+	if workers <= 1 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return hashPairs(bt.hasher, nodes)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		out   = make([]Element, count)
+		chunk = (count + workers - 1) / workers
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+	for from := 0; from < count; from += chunk {
+		to := min(from+chunk, count)
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			for i := from; i < to; i += minPairsPerWorker {
+				if err := ctx.Err(); err != nil {
+					fail(err)
+					return
+				}
+				end := min(i+minPairsPerWorker, to)
+				hashes, err := hashPairs(bt.hasher, nodes[i*2:end*2])
+				if err != nil {
+					fail(err)
+					return
+				}
+				copy(out[i:end], hashes)
+			}
+		}(from, to)
+	}
+	wg.Wait()
+	if first != nil {
+		return nil, first
+	}
+	return out, nil
+}
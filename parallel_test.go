@@ -0,0 +1,63 @@
+package fMerkleTree
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cancelingHasher cancels the build after a number of hashes
+type cancelingHasher struct {
+	Hasher
+	calls  *atomic.Int64
+	after  int64
+	cancel context.CancelFunc
+}
+
+func (h cancelingHasher) Hash(left Element, right Element) (Element, error) {
+	if h.calls.Add(1) == h.after {
+		h.cancel()
+	}
+	return h.Hasher.Hash(left, right)
+}
+
+func Test_ParallelBuild(t *testing.T) {
+	elements := newTestElements(1, 5000)
+
+	t.Run("should match the sequential build", func(t *testing.T) {
+		for _, hasher := range []Hasher{SHA256Hasher, PoseidonHasher} {
+			sequential, err := NewMerkleTreeWithHasher(13, elements, Element{0}, hasher)
+			require.NoError(t, err)
+			for _, workers := range []int{2, 7, 32} {
+				tree, err := NewMerkleTreeContext(context.Background(), 13, elements, Element{0}, hasher, WithWorkers(workers))
+				require.NoError(t, err)
+				require.Equal(t, workers, tree.Workers())
+				require.Equal(t, sequential.Layers(), tree.Layers())
+			}
+		}
+		_, err := NewMerkleTree(13, elements, Element{0}, SHA256Hash, WithWorkers(0))
+		require.Error(t, err)
+	})
+
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewMerkleTreeContext(ctx, 13, elements, Element{0}, SHA256Hasher, WithWorkers(4))
+		require.ErrorIs(t, err, context.Canceled)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		hasher := cancelingHasher{Hasher: SHA256Hasher, calls: &atomic.Int64{}, after: 100, cancel: cancel}
+		_, err = NewMerkleTreeContext(ctx, 13, elements, Element{0}, hasher, WithWorkers(4))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, hasher.calls.Load(), int64(len(elements)))
+	})
+
+	t.Run("should return hashing errors", func(t *testing.T) {
+		hasher := failingHasher{SHA256Hasher, elements[3000]}
+		_, err := NewMerkleTreeContext(context.Background(), 13, elements, Element{0}, hasher, WithWorkers(4))
+		require.ErrorContains(t, err, "cannot hash")
+	})
+}
@@ -0,0 +1,267 @@
+package fMerkleTree
+
+import (
+	"context"
+	"fmt"
+)
+
+type proofNode struct {
+	position int
+	element  Element
+}
+
+/**
+* Merkle tree that only knows the leaves from an edge onwards.
+* Nodes left of the edge are taken from the edge path, so every index at or past the edge
+* can be updated and proven without the leaves before it.
+ */
+type PartialMerkleTree struct {
+	*BaseTree
+	edgeIndex       int
+	edgeElement     Element
+	edgeLeafProof   ProofPath
+	leavesAfterEdge []Element
+	proofMap        map[int]proofNode
+}
+
+/**
+* Build a partial tree from a tree edge
+* @param levels Number of levels of the full tree
+* @param edge Edge returned by GetTreeSlices
+* @param leaves Elements from the edge index to the end of the tree, edge element included
+* @param zeroElement Value for non-existent leaves
+* @param hashFn Hash function the full tree was built with
+* @param opts Options the full tree was built with, such as WithHashDomain, WithFieldModulus or WithFixedWidth
+ */
+func NewPartialMerkleTree(levels int, edge TreeEdge, leaves []Element, zeroElement Element, hashFn HashFunction, opts ...TreeOption) (*PartialMerkleTree, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	if edge.EdgeIndex+len(leaves) != edge.EdgeElementsCount {
+		return nil, fmt.Errorf("invalid number of elements")
+	}
+	if len(edge.EdgePath.PathElements) != levels || len(edge.EdgePath.PathIndices) != levels {
+		return nil, fmt.Errorf("edge path does not match levels")
+	}
+	out := &PartialMerkleTree{
+		BaseTree: &BaseTree{
+			levels:      levels,
+			zeroElement: zeroElement,
+		},
+		edgeIndex:       edge.EdgeIndex,
+		edgeElement:     edge.EdgeElement,
+		edgeLeafProof:   edge.EdgePath,
+		leavesAfterEdge: append([]Element{}, leaves...),
+	}
+	if out.edgeIndex >= out.Capacity() {
+		return nil, fmt.Errorf("tree is full")
+	}
+	out.setHasher(hasherOf(hashFn))
+	for _, opt := range opts {
+		if err := opt(out.BaseTree); err != nil {
+			return nil, err
+		}
+	}
+	if err := out.checkField(-1, zeroElement); err != nil {
+		return nil, err
+	}
+	if err := out.buildZeros(); err != nil {
+		return nil, err
+	}
+	out.createProofMap()
+	if err := out.buildTree(leaves); err != nil {
+		return nil, err
+	}
+	if !out.Root().Cmp(edge.EdgePath.PathRoot) {
+		return nil, fmt.Errorf("root mismatch")
+	}
+	return out, nil
+}
+
+func (pt PartialMerkleTree) EdgeIndex() int {
+	return pt.edgeIndex
+}
+
+func (pt PartialMerkleTree) EdgeElement() Element {
+	return pt.edgeElement
+}
+
+func (pt PartialMerkleTree) EdgeLeafProof() ProofPath {
+	return pt.edgeLeafProof
+}
+
+// createProofMap keeps the edge path siblings that lie left of the edge, they are the only
+// nodes that cannot be recomputed from the known leaves
+func (pt *PartialMerkleTree) createProofMap() {
+	pt.proofMap = make(map[int]proofNode, pt.levels)
+	for level := 0; level < pt.levels; level++ {
+		if pt.edgeLeafProof.PathIndices[level] == 1 {
+			pt.proofMap[level] = proofNode{
+				position: (pt.edgeIndex >> level) ^ 1,
+				element:  pt.edgeLeafProof.PathElements[level],
+			}
+		}
+	}
+}
+
+// buildTree computes the layers from the elements after the edge, the nodes left of the edge stay nil
+func (pt *PartialMerkleTree) buildTree(leavesAfterEdge []Element) error {
+	leaves := make([]Element, pt.edgeIndex, pt.edgeIndex+len(leavesAfterEdge))
+	for i, element := range leavesAfterEdge {
+		node, err := pt.leafNode(pt.edgeIndex+i, element)
+		if err != nil {
+			return err
+		}
+		leaves = append(leaves, node)
+	}
+	if node, ok := pt.proofMap[0]; ok {
+		leaves[node.position] = node.element
+	}
+	if pt.domain != nil {
+		pt.leaves = append(make([]Element, pt.edgeIndex, pt.edgeIndex+len(leavesAfterEdge)), leavesAfterEdge...)
+	}
+	pt.layers = make([][]Element, pt.levels+1)
+	pt.layers[0] = leaves
+	for layerIndex := 1; layerIndex <= pt.levels; layerIndex++ {
+		layer, err := pt.processNodes(context.Background(), pt.layers[layerIndex-1], layerIndex)
+		if err != nil {
+			return err
+		}
+		if node, ok := pt.proofMap[layerIndex]; ok && layer[node.position] == nil {
+			layer[node.position] = node.element
+		}
+		pt.layers[layerIndex] = layer
+	}
+	if pt.width > 0 {
+		for level, layer := range pt.layers {
+			if err := pt.storeLayer(level, layer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/**
+* Insert new element into the tree
+* @param element Element to insert
+ */
+func (pt *PartialMerkleTree) Insert(element Element) error {
+	if pt.layerLen(0) >= pt.Capacity() {
+		return fmt.Errorf("tree is full")
+	}
+	return pt.Update(pt.layerLen(0), element)
+}
+
+/**
+* Insert multiple elements into the tree.
+* @param elements Elements to insert
+ */
+func (pt *PartialMerkleTree) BulkInsert(elements []Element) error {
+	_, err := pt.Append(elements)
+	return err
+}
+
+/**
+* Change an element in the tree
+* @param index Index of element to change, must not be below the edge
+* @param element Updated element value
+ */
+func (pt *PartialMerkleTree) Update(index int, element Element) error {
+	if index < pt.edgeIndex {
+		return fmt.Errorf("index %d is below the edge: %d", index, pt.edgeIndex)
+	}
+	return pt.BaseTree.Update(index, element)
+}
+
+/**
+* Change several elements at once, see BaseTree.BatchUpdate
+* @param updates New element values by index, no index may be below the edge
+ */
+func (pt *PartialMerkleTree) BatchUpdate(updates map[int]Element) error {
+	_, err := pt.BatchUpdateRoots(updates)
+	return err
+}
+
+func (pt *PartialMerkleTree) BatchUpdateRoots(updates map[int]Element) (RootUpdate, error) {
+	for index := range updates {
+		if index < pt.edgeIndex {
+			return RootUpdate{}, fmt.Errorf("index %d is below the edge: %d", index, pt.edgeIndex)
+		}
+	}
+	return pt.BaseTree.BatchUpdateRoots(updates)
+}
+
+/**
+* Get merkle path to a leaf
+* @param index Leaf index to generate path for, must not be below the edge
+ */
+func (pt *PartialMerkleTree) Path(index int) (ProofPath, error) {
+	if index < pt.edgeIndex && index >= 0 {
+		return ProofPath{}, fmt.Errorf("index %d is below the edge: %d", index, pt.edgeIndex)
+	}
+	return pt.BaseTree.Path(index)
+}
+
+func (pt PartialMerkleTree) IndexOf(element Element) int {
+	return IndexOfElement(pt.leafValues(), element, pt.edgeIndex, nil)
+}
+
+func (pt PartialMerkleTree) Proof(element Element) (ProofPath, error) {
+	return pt.Path(pt.IndexOf(element))
+}
+
+/**
+* Extend the known range backwards with an earlier slice
+* The tree the edges were taken from is rebuilt with elements and checked against the root of the current edge,
+* the partial tree is left unchanged on a mismatch. Elements inserted or updated since the tree was built are kept
+* @param edge Edge of the earlier slice, taken from the same tree state as the current edge
+* @param elements Elements from the new edge up to the current edge
+ */
+func (pt *PartialMerkleTree) ShiftEdge(edge TreeEdge, elements []Element) error {
+	if pt.edgeIndex <= edge.EdgeIndex {
+		return fmt.Errorf("new edge index should be smaller than %d", pt.edgeIndex)
+	}
+	if len(elements) != pt.edgeIndex-edge.EdgeIndex {
+		return fmt.Errorf("elements length should be %d", pt.edgeIndex-edge.EdgeIndex)
+	}
+	if len(edge.EdgePath.PathElements) != pt.levels || len(edge.EdgePath.PathIndices) != pt.levels {
+		return fmt.Errorf("edge path does not match levels")
+	}
+	if edge.EdgeElementsCount != pt.edgeIndex+len(pt.leavesAfterEdge) || !edge.EdgePath.PathRoot.Cmp(pt.edgeLeafProof.PathRoot) {
+		return fmt.Errorf("edge is from a different tree state")
+	}
+	if !elements[0].Cmp(edge.EdgeElement) {
+		return fmt.Errorf("elements do not start with the edge element")
+	}
+	base := *pt.BaseTree
+	base.storage, base.lengths, base.history, base.deferred = nil, nil, nil, nil
+	check := &PartialMerkleTree{BaseTree: &base, edgeIndex: edge.EdgeIndex, edgeLeafProof: edge.EdgePath}
+	check.createProofMap()
+	if err := check.buildTree(append(append([]Element{}, elements...), pt.leavesAfterEdge...)); err != nil {
+		return err
+	}
+	if !check.Root().Cmp(pt.edgeLeafProof.PathRoot) {
+		return fmt.Errorf("elements do not match the edge")
+	}
+	next := *pt.BaseTree
+	next.storage, next.lengths = nil, nil
+	shifted := &PartialMerkleTree{
+		BaseTree:        &next,
+		edgeIndex:       edge.EdgeIndex,
+		edgeElement:     edge.EdgeElement,
+		edgeLeafProof:   edge.EdgePath,
+		leavesAfterEdge: append(append([]Element{}, elements...), pt.leavesAfterEdge...),
+	}
+	shifted.createProofMap()
+	if err := shifted.buildTree(append(append([]Element{}, elements...), pt.leafValues()[pt.edgeIndex:]...)); err != nil {
+		return err
+	}
+	*pt.BaseTree = next
+	pt.edgeIndex = shifted.edgeIndex
+	pt.edgeElement = shifted.edgeElement
+	pt.edgeLeafProof = shifted.edgeLeafProof
+	pt.leavesAfterEdge = shifted.leavesAfterEdge
+	pt.proofMap = shifted.proofMap
+	return nil
+}
@@ -0,0 +1,149 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestElements(from, to int) []Element {
+	out := make([]Element, 0, to-from)
+	for i := from; i < to; i++ {
+		out = append(out, Element{byte(i + 1)})
+	}
+	return out
+}
+
+func Test_PartialMerkleTree(t *testing.T) {
+	newTrees := func(t *testing.T, edgeSlice int) (*MerkleTree, *PartialMerkleTree, []TreeSlice) {
+		tree, err := NewMerkleTree(10, newTestElements(0, 21), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		slices, err := tree.GetTreeSlices(4)
+		require.NoError(t, err)
+		edge := slices[edgeSlice].Edge
+		partial, err := NewPartialMerkleTree(10, edge, tree.Elements()[edge.EdgeIndex:], Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		return tree, partial, slices
+	}
+
+	t.Run("should have the same root as the full tree", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			tree, partial, _ := newTrees(t, i)
+			require.Equal(t, tree.Root(), partial.Root())
+		}
+	})
+
+	t.Run("should insert, update and prove like the full tree", func(t *testing.T) {
+		tree, partial, _ := newTrees(t, 2)
+		require.NoError(t, tree.Insert(Element{42}))
+		require.NoError(t, partial.Insert(Element{42}))
+		require.NoError(t, tree.BulkInsert([]Element{{43}, {44}, {45}}))
+		require.NoError(t, partial.BulkInsert([]Element{{43}, {44}, {45}}))
+		require.NoError(t, tree.Update(partial.EdgeIndex()+1, Element{46}))
+		require.NoError(t, partial.Update(partial.EdgeIndex()+1, Element{46}))
+		require.Equal(t, tree.Root(), partial.Root())
+
+		for i := partial.EdgeIndex(); i < len(tree.Elements()); i++ {
+			expected, err := tree.Path(i)
+			require.NoError(t, err)
+			path, err := partial.Path(i)
+			require.NoError(t, err)
+			require.Equal(t, expected, path)
+		}
+		proof, err := partial.Proof(Element{45})
+		require.NoError(t, err)
+		require.NoError(t, VerifyPath(Element{45}, proof, tree.Root(), SHA256Hash))
+	})
+
+	t.Run("should reject indices below the edge", func(t *testing.T) {
+		_, partial, _ := newTrees(t, 1)
+		require.Error(t, partial.Update(partial.EdgeIndex()-1, Element{42}))
+		_, err := partial.Path(partial.EdgeIndex() - 1)
+		require.Error(t, err)
+		require.Equal(t, -1, partial.IndexOf(Element{1}))
+	})
+
+	t.Run("should extend the edge backwards", func(t *testing.T) {
+		tree, partial, slices := newTrees(t, 3)
+		require.NoError(t, partial.Insert(Element{42}))
+		require.NoError(t, tree.Insert(Element{42}))
+
+		require.Error(t, partial.ShiftEdge(slices[3].Edge, slices[3].Elements))
+		require.Error(t, partial.ShiftEdge(slices[1].Edge, slices[2].Elements))
+		for i := 2; i >= 0; i-- {
+			require.NoError(t, partial.ShiftEdge(slices[i].Edge, slices[i].Elements))
+			require.Equal(t, slices[i].Edge.EdgeIndex, partial.EdgeIndex())
+			require.Equal(t, tree.Root(), partial.Root())
+		}
+		require.Equal(t, tree.Elements(), partial.Elements())
+		path, err := partial.Path(0)
+		require.NoError(t, err)
+		expected, err := tree.Path(0)
+		require.NoError(t, err)
+		require.Equal(t, expected, path)
+	})
+
+	t.Run("should reject inconsistent input", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, newTestElements(0, 21), Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		slices, err := tree.GetTreeSlices(4)
+		require.NoError(t, err)
+		edge := slices[1].Edge
+		_, err = NewPartialMerkleTree(10, edge, tree.Elements()[edge.EdgeIndex+1:], Element{0}, SHA256Hash)
+		require.Error(t, err)
+		leaves := append([]Element{}, tree.Elements()[edge.EdgeIndex:]...)
+		leaves[3] = Element{99}
+		_, err = NewPartialMerkleTree(10, edge, leaves, Element{0}, SHA256Hash)
+		require.Error(t, err)
+	})
+
+	t.Run("should not shift the edge with wrong elements", func(t *testing.T) {
+		tree, partial, slices := newTrees(t, 2)
+		require.NoError(t, partial.Insert(Element{42}))
+		require.NoError(t, tree.Insert(Element{42}))
+		root := partial.Root()
+		elements := append([]Element{}, slices[1].Elements...)
+		elements[2] = Element{99}
+		require.Error(t, partial.ShiftEdge(slices[1].Edge, elements))
+		require.Equal(t, slices[2].Edge.EdgeIndex, partial.EdgeIndex())
+		require.Equal(t, root, partial.Root())
+		_, err := partial.Path(slices[1].Edge.EdgeIndex)
+		require.Error(t, err)
+
+		require.NoError(t, partial.ShiftEdge(slices[1].Edge, slices[1].Elements))
+		require.Equal(t, tree.Root(), partial.Root())
+	})
+
+	t.Run("should build from trees with options", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{{WithHashDomain(RFC6962)}, {WithFixedWidth()}, {WithBN254Field()}} {
+			tree, err := NewMerkleTree(10, newTestElements(0, 21), Element{0}, Poseidon, opts...)
+			require.NoError(t, err)
+			slices, err := tree.GetTreeSlices(4)
+			require.NoError(t, err)
+			edge := slices[2].Edge
+			partial, err := NewPartialMerkleTree(10, edge, tree.Elements()[edge.EdgeIndex:], Element{0}, Poseidon, opts...)
+			require.NoError(t, err)
+			require.Equal(t, tree.Root(), partial.Root())
+
+			require.NoError(t, tree.Insert(Element{42}))
+			require.NoError(t, partial.Insert(Element{42}))
+			require.NoError(t, partial.ShiftEdge(slices[1].Edge, slices[1].Elements))
+			require.Equal(t, tree.Root(), partial.Root())
+			require.Equal(t, tree.Elements()[slices[1].Edge.EdgeIndex:], partial.Elements()[slices[1].Edge.EdgeIndex:])
+			require.Equal(t, edge.EdgeIndex, partial.IndexOf(tree.Elements()[edge.EdgeIndex]))
+			for _, index := range []int{slices[1].Edge.EdgeIndex, 21} {
+				expected, err := tree.Path(index)
+				require.NoError(t, err)
+				path, err := partial.Path(index)
+				require.NoError(t, err)
+				require.Equal(t, expected, path)
+			}
+		}
+		tree, err := NewMerkleTree(10, newTestElements(0, 21), Element{0}, Poseidon)
+		require.NoError(t, err)
+		slices, err := tree.GetTreeSlices(4)
+		require.NoError(t, err)
+		_, err = NewPartialMerkleTree(10, slices[2].Edge, append(slices[2].Elements, slices[3].Elements...), Element{0}, Poseidon, WithHashDomain(RFC6962))
+		require.Error(t, err)
+	})
+}
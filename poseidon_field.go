@@ -0,0 +1,150 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0xbow-io/go-iden3-crypto/ff"
+	"github.com/0xbow-io/go-iden3-crypto/poseidon"
+)
+
+// poseidonT3 holds the Poseidon round constants and matrices for two inputs in Montgomery form
+var poseidonT3 struct {
+	c []ff.Element
+	s []ff.Element
+	m [3][3]ff.Element
+	p [3][3]ff.Element
+}
+
+func init() {
+	parse := func(s string) ff.Element {
+		b, ok := new(big.Int).SetString(s, 16)
+		if !ok {
+			panic(fmt.Errorf("error parsing poseidon constant %s", s))
+		}
+		var e ff.Element
+		e.SetBigInt(b)
+		return e
+	}
+	poseidonT3.c = make([]ff.Element, len(poseidonT3Hex.c))
+	for i, s := range poseidonT3Hex.c {
+		poseidonT3.c[i] = parse(s)
+	}
+	poseidonT3.s = make([]ff.Element, len(poseidonT3Hex.s))
+	for i, s := range poseidonT3Hex.s {
+		poseidonT3.s[i] = parse(s)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			poseidonT3.m[i][j] = parse(poseidonT3Hex.m[i][j])
+			poseidonT3.p[i][j] = parse(poseidonT3Hex.p[i][j])
+		}
+	}
+}
+
+/**
+* Poseidon hash of two field elements, the same function as Poseidon without leaving Montgomery form
+* Follows poseidon.Hash for t = 3 step by step and works on values instead of pointers so it does not allocate
+ */
+func poseidonField(left, right *ff.Element) ff.Element {
+	const t = 3
+	nRoundsF := poseidon.NROUNDSF
+	nRoundsP := poseidon.NROUNDSP[t-2]
+	C, S := poseidonT3.c, poseidonT3.s
+
+	var state [t]ff.Element
+	state[1], state[2] = *left, *right
+	arkField(&state, C[0:t])
+
+	for i := 0; i < nRoundsF/2-1; i++ {
+		exp5Field(&state)
+		arkField(&state, C[(i+1)*t:])
+		mixField(&state, &poseidonT3.m)
+	}
+	exp5Field(&state)
+	arkField(&state, C[(nRoundsF/2)*t:])
+	mixField(&state, &poseidonT3.p)
+
+	var mul, state0 ff.Element
+	for i := 0; i < nRoundsP; i++ {
+		pow5(&state[0])
+		state[0].Add(&state[0], &C[(nRoundsF/2+1)*t+i])
+
+		state0.SetZero()
+		for j := 0; j < t; j++ {
+			mul.Mul(&S[(t*2-1)*i+j], &state[j])
+			state0.Add(&state0, &mul)
+		}
+		for k := 1; k < t; k++ {
+			mul.Mul(&state[0], &S[(t*2-1)*i+t+k-1])
+			state[k].Add(&state[k], &mul)
+		}
+		state[0] = state0
+	}
+
+	for i := 0; i < nRoundsF/2-1; i++ {
+		exp5Field(&state)
+		arkField(&state, C[(nRoundsF/2+1)*t+nRoundsP+i*t:])
+		mixField(&state, &poseidonT3.m)
+	}
+	exp5Field(&state)
+	mixField(&state, &poseidonT3.m)
+	return state[0]
+}
+
+// pow5 sets x to x^5 with two squarings and a multiplication
+func pow5(x *ff.Element) {
+	var x4 ff.Element
+	x4.Square(x)
+	x4.Square(&x4)
+	x.Mul(x, &x4)
+}
+
+func exp5Field(state *[3]ff.Element) {
+	for i := range state {
+		pow5(&state[i])
+	}
+}
+
+func arkField(state *[3]ff.Element, c []ff.Element) {
+	for i := range state {
+		state[i].Add(&state[i], &c[i])
+	}
+}
+
+// mixField multiplies the state by the transposed matrix m, as poseidon's mix does
+func mixField(state *[3]ff.Element, m *[3][3]ff.Element) {
+	var out [3]ff.Element
+	var mul ff.Element
+	for i := range out {
+		for j := range state {
+			mul.Mul(&m[j][i], &state[j])
+			out[i].Add(&out[i], &mul)
+		}
+	}
+	*state = out
+}
+
+/**
+* Convert an Element to a field element in Montgomery form
+* Returns a *FieldError for elements that are not smaller than BN254ScalarField, as Poseidon rejects them
+* @param index Leaf index reported in the error, -1 for the zero element
+ */
+func toFieldElement(index int, element Element) (ff.Element, error) {
+	var out ff.Element
+	if !InField(element, BN254ScalarField) {
+		return out, &FieldError{Index: index, Element: element, Modulus: BN254ScalarField}
+	}
+	out.SetBytes(element)
+	return out, nil
+}
+
+// fromFieldElement converts a field element to the minimal big-endian encoding Poseidon returns
+func fromFieldElement(e *ff.Element) Element {
+	b := e.Bytes()
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+	return append(Element{}, b[i:]...)
+}
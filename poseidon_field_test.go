@@ -0,0 +1,53 @@
+package fMerkleTree
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/0xbow-io/go-iden3-crypto/poseidon"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PoseidonField(t *testing.T) {
+	largest := Element(new(big.Int).Sub(BN254ScalarField, big.NewInt(1)).Bytes())
+
+	t.Run("should match Poseidon", func(t *testing.T) {
+		inputs := []Element{{}, {0}, {1}, {0, 0, 7}, largest, Poseidon(Element{1}, Element{2})}
+		for _, left := range inputs {
+			for _, right := range inputs {
+				l, err := toFieldElement(0, left)
+				require.NoError(t, err)
+				r, err := toFieldElement(1, right)
+				require.NoError(t, err)
+				hash := poseidonField(&l, &r)
+				require.Equal(t, Element(Poseidon(left, right)), fromFieldElement(&hash))
+			}
+		}
+	})
+
+	t.Run("should match poseidon.Hash for random inputs", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 200; i++ {
+			left := new(big.Int).Rand(rng, BN254ScalarField)
+			right := new(big.Int).Rand(rng, BN254ScalarField)
+			expected, err := poseidon.Hash([]*big.Int{left, right})
+			require.NoError(t, err)
+			l, err := toFieldElement(0, left.Bytes())
+			require.NoError(t, err)
+			r, err := toFieldElement(1, right.Bytes())
+			require.NoError(t, err)
+			hash := poseidonField(&l, &r)
+			require.Equal(t, Element(expected.Bytes()), fromFieldElement(&hash))
+		}
+	})
+
+	t.Run("should reject elements outside the field", func(t *testing.T) {
+		_, err := toFieldElement(3, Element(BN254ScalarField.Bytes()))
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		require.Equal(t, 3, fieldErr.Index)
+		_, err = toFieldElement(0, make(Element, 33))
+		require.NoError(t, err)
+	})
+}
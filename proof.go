@@ -0,0 +1,81 @@
+package fMerkleTree
+
+import (
+	"fmt"
+)
+
+/**
+* Compute the root implied by a leaf and its merkle path
+* A panic inside hashFn is returned as an error
+* @param leaf Leaf value the path was generated for
+* @param hashFn Hash function the tree was built with
+* @returns Root obtained by folding PathElements and PathIndices from the leaf upwards
+ */
+func (p ProofPath) ComputeRoot(leaf Element, hashFn HashFunction) (Element, error) {
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	return p.ComputeRootWithHasher(leaf, hasherOf(hashFn))
+}
+
+// ComputeRootWithHasher is ComputeRoot returning the errors of hasher
+func (p ProofPath) ComputeRootWithHasher(leaf Element, hasher Hasher) (Element, error) {
+	if hasher == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	if len(p.PathElements) != len(p.PathIndices) {
+		return nil, fmt.Errorf("invalid proof: %d path elements, %d path indices", len(p.PathElements), len(p.PathIndices))
+	}
+	node := leaf
+	for level, sibling := range p.PathElements {
+		var err error
+		switch p.PathIndices[level] {
+		case 0:
+			node, err = hasher.Hash(node, sibling)
+		case 1:
+			node, err = hasher.Hash(sibling, node)
+		default:
+			return nil, fmt.Errorf("invalid proof: path index %d at level %d", p.PathIndices[level], level)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+/**
+* Verify a merkle path against a trusted root without access to the tree
+* @param leaf Leaf value the path was generated for
+* @param root Trusted root, e.g. read from chain
+* @param hashFn Hash function the tree was built with
+ */
+func (p ProofPath) Verify(leaf Element, root Element, hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	return p.VerifyWithHasher(leaf, root, hasherOf(hashFn))
+}
+
+// VerifyWithHasher is Verify returning the errors of hasher
+func (p ProofPath) VerifyWithHasher(leaf Element, root Element, hasher Hasher) error {
+	computed, err := p.ComputeRootWithHasher(leaf, hasher)
+	if err != nil {
+		return err
+	}
+	if !computed.Cmp(root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
+
+/**
+* Stateless counterpart of BaseTree.VerifyProof
+* @param leaf Leaf value the path was generated for
+* @param proof Merkle path of the leaf
+* @param root Trusted root
+* @param hashFn Hash function the tree was built with
+ */
+func VerifyPath(leaf Element, proof ProofPath, root Element, hashFn HashFunction) error {
+	return proof.Verify(leaf, root, hashFn)
+}
@@ -0,0 +1,51 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_VerifyPath(t *testing.T) {
+	t.Run("should verify every leaf against the root", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {4}, {5}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		for i, leaf := range tree.Elements() {
+			path, err := tree.Path(i)
+			require.NoError(t, err)
+			require.NoError(t, VerifyPath(leaf, path, tree.Root(), SHA256Hash))
+			require.NoError(t, path.Verify(leaf, path.PathRoot, SHA256Hash))
+		}
+	})
+
+	t.Run("should work with poseidon", func(t *testing.T) {
+		tree, err := NewMerkleTree(20, []Element{{1}, {2}, {3}}, Element{0}, Poseidon)
+		require.NoError(t, err)
+		path, err := tree.Path(2)
+		require.NoError(t, err)
+		root, err := path.ComputeRoot(Element{3}, Poseidon)
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), root)
+	})
+
+	t.Run("should reject wrong leaf or root", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {4}, {5}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		path, err := tree.Path(3)
+		require.NoError(t, err)
+		require.Error(t, VerifyPath(Element{5}, path, tree.Root(), SHA256Hash))
+		require.Error(t, VerifyPath(Element{4}, path, Element{1}, SHA256Hash))
+	})
+
+	t.Run("should reject malformed proofs", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		path, err := tree.Path(0)
+		require.NoError(t, err)
+		path.PathIndices[0] = 2
+		require.Error(t, VerifyPath(Element{1}, path, tree.Root(), SHA256Hash))
+		path.PathIndices = path.PathIndices[:1]
+		require.Error(t, VerifyPath(Element{1}, path, tree.Root(), SHA256Hash))
+		require.Error(t, VerifyPath(Element{1}, path, tree.Root(), nil))
+	})
+}
@@ -0,0 +1,120 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	registryMu     sync.RWMutex
+	hasherRegistry = map[string]Hasher{}
+	// builtinHashFunctions maps the hash functions of this package to their hashers
+	builtinHashFunctions = map[uintptr]Hasher{}
+)
+
+func init() {
+	for _, builtin := range []struct {
+		hashFn HashFunction
+		hasher Hasher
+	}{
+		{SHA256Hash, SHA256Hasher},
+		{SHA256ModP, SHA256ModPHasher},
+		{Keccak256, Keccak256Hasher},
+		{Poseidon, PoseidonHasher},
+		{Poseidon2, Poseidon2Hasher},
+		{MIMC7, MIMC7Hasher},
+	} {
+		hasherRegistry[builtin.hasher.Name()] = builtin.hasher
+		builtinHashFunctions[reflect.ValueOf(builtin.hashFn).Pointer()] = builtin.hasher
+	}
+}
+
+/**
+* Register a hasher so that trees built with it can be deserialized without passing it
+* @param hasher Hasher with a unique name
+ */
+func RegisterHasher(hasher Hasher) error {
+	if hasher == nil || hasher.Name() == "" {
+		return fmt.Errorf("hasher needs a name")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := hasherRegistry[hasher.Name()]; ok {
+		return fmt.Errorf("hasher %s is already registered", hasher.Name())
+	}
+	hasherRegistry[hasher.Name()] = hasher
+	return nil
+}
+
+// LookupHasher returns the registered hasher with the given name
+func LookupHasher(name string) (Hasher, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	hasher, ok := hasherRegistry[name]
+	return hasher, ok
+}
+
+// hasherOf adapts hashFn for trees that are built from a bare HashFunction
+// The hash functions of this package keep their names, any other function gets a hasher without a name
+func hasherOf(hashFn HashFunction) Hasher {
+	if hasher, ok := builtinHashFunctions[reflect.ValueOf(hashFn).Pointer()]; ok {
+		return hasher
+	}
+	return NewHasher("", 0, hashFn)
+}
+
+/**
+* Pick the hasher of a serialized tree built with the hasher called name
+* Without a hasher the name must be registered. A hasher without a name is accepted for any tree,
+* the tree fingerprint is checked against it afterwards
+ */
+func resolveHasher(name string, hasher Hasher) (Hasher, error) {
+	if hasher == nil {
+		if name == "" {
+			return nil, fmt.Errorf("tree has no hash name, the hash function must be given")
+		}
+		registered, ok := LookupHasher(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown hash function %s, register it or pass its hasher", name)
+		}
+		return registered, nil
+	}
+	if name != "" && hasher.Name() != "" && hasher.Name() != name {
+		return nil, fmt.Errorf("hash function mismatch: tree was built with %s, got %s", name, hasher.Name())
+	}
+	return hasher, nil
+}
+
+// HashName returns the name of the hasher the tree was built with, before sorted pairs or a hash domain were applied,
+// empty for trees built from a HashFunction that is not one of this package
+func (bt BaseTree) HashName() string {
+	return bt.hashName
+}
+
+// fingerprint identifies the hasher and zero element of the tree, it is the root of an empty tree
+func (bt BaseTree) fingerprint() Element {
+	return bt.zeros[bt.levels]
+}
+
+// checkFingerprint recomputes the empty root with the hasher of the tree and compares it to fingerprint
+func (bt BaseTree) checkFingerprint(fingerprint Element) error {
+	if fingerprint == nil {
+		return nil
+	}
+	node := bt.zeros[0]
+	for level := 0; level < bt.levels; level++ {
+		var err error
+		if node, err = bt.hasher.Hash(node, node); err != nil {
+			return err
+		}
+	}
+	if !node.Cmp(fingerprint) {
+		name := bt.hasher.Name()
+		if name == "" {
+			name = "the hash function"
+		}
+		return fmt.Errorf("hash function mismatch: %s does not reproduce the tree fingerprint", name)
+	}
+	return nil
+}
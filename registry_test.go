@@ -0,0 +1,124 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HasherRegistry(t *testing.T) {
+	elements := []Element{{1}, {2}, {3}}
+
+	t.Run("should name the builtin hashers", func(t *testing.T) {
+		for name, hasher := range map[string]Hasher{
+			"sha256":    SHA256Hasher,
+			"poseidon":  PoseidonHasher,
+			"poseidon2": Poseidon2Hasher,
+			"mimc7":     MIMC7Hasher,
+			"keccak256": Keccak256Hasher,
+		} {
+			tree, err := NewMerkleTreeWithHasher(4, elements, Element{0}, hasher)
+			require.NoError(t, err)
+			require.Equal(t, name, tree.HashName())
+			registered, ok := LookupHasher(name)
+			require.True(t, ok)
+			require.Equal(t, name, registered.Name())
+		}
+		for name, hashFn := range map[string]HashFunction{
+			"sha256":    SHA256Hash,
+			"poseidon":  Poseidon,
+			"poseidon2": Poseidon2,
+			"mimc7":     MIMC7,
+			"keccak256": Keccak256,
+		} {
+			tree, err := NewMerkleTree(4, elements, Element{0}, hashFn)
+			require.NoError(t, err)
+			require.Equal(t, name, tree.HashName())
+		}
+		for _, hashFn := range []HashFunction{SortedPairHash(SHA256Hash), func(left Element, right Element) []byte { return SHA256Hash(left, right) }} {
+			tree, err := NewMerkleTree(4, elements, Element{0}, hashFn)
+			require.NoError(t, err)
+			require.Equal(t, "", tree.HashName())
+		}
+	})
+
+	t.Run("should resolve the hash function on deserialization", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, elements, Element{0}, PoseidonHasher)
+		require.NoError(t, err)
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, nil)
+		require.NoError(t, err)
+		require.Equal(t, "poseidon", tree2.HashName())
+		require.NoError(t, tree.Insert(Element{4}))
+		require.NoError(t, tree2.Insert(Element{4}))
+		require.Equal(t, tree.Root(), tree2.Root())
+
+		tree, err = NewMerkleTree(4, elements, Element{0}, Poseidon)
+		require.NoError(t, err)
+		data, err = tree.Serialize()
+		require.NoError(t, err)
+		tree2, err = DeserializeMerkleTree(data, nil)
+		require.NoError(t, err)
+		require.Equal(t, "poseidon", tree2.HashName())
+		require.Equal(t, tree.Root(), tree2.Root())
+	})
+
+	t.Run("should reject a different hash function", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, elements, Element{0}, SHA256Hasher)
+		require.NoError(t, err)
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		_, err = DeserializeMerkleTreeWithHasher(data, PoseidonHasher)
+		require.ErrorContains(t, err, "tree was built with sha256, got poseidon")
+		_, err = DeserializeMerkleTree(data, Poseidon)
+		require.ErrorContains(t, err, "tree was built with sha256, got poseidon")
+		_, err = DeserializeMerkleTree(data, func(left Element, right Element) []byte { return Keccak256(left, right) })
+		require.ErrorContains(t, err, "hash function mismatch")
+		_, err = DeserializeMerkleTree(data, func(left Element, right Element) []byte { return SHA256Hash(left, right) })
+		require.NoError(t, err)
+
+		custom, err := NewMerkleTree(4, elements, Element{0}, SortedPairHash(SHA256Hash))
+		require.NoError(t, err)
+		data, err = custom.Serialize()
+		require.NoError(t, err)
+		_, err = DeserializeMerkleTree(data, nil)
+		require.ErrorContains(t, err, "tree has no hash name")
+		_, err = DeserializeMerkleTree(data, Keccak256)
+		require.ErrorContains(t, err, "hash function mismatch")
+		_, err = DeserializeMerkleTree(data, SortedPairHash(SHA256Hash))
+		require.NoError(t, err)
+
+		unknown, err := NewMerkleTreeWithHasher(4, elements, Element{0}, NewHasher("test-unregistered", 32, SHA256Hash))
+		require.NoError(t, err)
+		data, err = unknown.Serialize()
+		require.NoError(t, err)
+		_, err = DeserializeMerkleTree(data, nil)
+		require.ErrorContains(t, err, "unknown hash function test-unregistered")
+		_, err = DeserializeMerkleTreeWithHasher(data, SHA256Hasher)
+		require.ErrorContains(t, err, "tree was built with test-unregistered, got sha256")
+		tree2, err := DeserializeMerkleTreeWithHasher(data, NewHasher("test-unregistered", 32, SHA256Hash))
+		require.NoError(t, err)
+		require.Equal(t, "test-unregistered", tree2.HashName())
+	})
+
+	t.Run("should register hashers", func(t *testing.T) {
+		hasher := NewHasher("test-reversed-sha256", 32, func(left Element, right Element) []byte {
+			return SHA256Hash(right, left)
+		})
+		require.NoError(t, RegisterHasher(hasher))
+		require.Error(t, RegisterHasher(hasher))
+		require.Error(t, RegisterHasher(NewHasher("", 32, SHA256Hash)))
+
+		tree, err := NewMerkleTreeWithHasher(4, elements, Element{0}, hasher, WithSortedPairs())
+		require.NoError(t, err)
+		require.Equal(t, "test-reversed-sha256", tree.HashName())
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, nil)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(Element{4}))
+		require.NoError(t, tree2.Insert(Element{4}))
+		require.Equal(t, tree.Root(), tree2.Root())
+	})
+}
@@ -0,0 +1,71 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LiveElement is a leaf that has not been removed, together with its index
+type LiveElement struct {
+	Index   int     `json:"index"`
+	Element Element `json:"element"`
+}
+
+/**
+* Make IndexOf and Proof skip removed leaves
+* Elements keeps one entry per leaf index, use LiveElements to list the leaves that are not removed
+ */
+func WithSkipRemoved() TreeOption {
+	return func(bt *BaseTree) error {
+		bt.skipRemoved = true
+		return nil
+	}
+}
+
+/**
+* Remove a leaf by setting it to the zero element
+* The slot stays occupied and is reported as a tombstone until it is updated again
+* @param index Index of the element to remove
+ */
+func (bt *BaseTree) Remove(index int) error {
+	if index < 0 || index >= bt.layerLen(0) {
+		return fmt.Errorf("index out of bounds: %d", index)
+	}
+	if err := bt.Update(index, bt.zeroElement); err != nil {
+		return err
+	}
+	if bt.tombstones == nil {
+		bt.tombstones = make(map[int]bool)
+	}
+	bt.tombstones[index] = true
+	return nil
+}
+
+func (bt BaseTree) IsRemoved(index int) bool {
+	return bt.tombstones[index]
+}
+
+// Tombstones returns the removed leaf indices in ascending order
+func (bt BaseTree) Tombstones() []int {
+	out := make([]int, 0, len(bt.tombstones))
+	for index := range bt.tombstones {
+		out = append(out, index)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func (bt BaseTree) TombstoneCount() int {
+	return len(bt.tombstones)
+}
+
+// LiveElements returns the leaves that are not removed with their indices, in ascending index order
+func (bt BaseTree) LiveElements() []LiveElement {
+	out := make([]LiveElement, 0, bt.layerLen(0)-len(bt.tombstones))
+	for i, element := range bt.leafValues() {
+		if !bt.tombstones[i] {
+			out = append(out, LiveElement{Index: i, Element: bt.detach(element)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MerkleTree_Remove(t *testing.T) {
+	t.Run("should set the leaf to the zero element", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {4}, {5}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(1))
+		expected, err := NewMerkleTree(10, []Element{{1}, {0}, {3}, {4}, {5}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), tree.Root())
+		require.True(t, tree.IsRemoved(1))
+		require.Equal(t, 1, tree.TombstoneCount())
+		require.Len(t, tree.Elements(), 5)
+
+		require.Error(t, tree.Remove(5))
+		require.NoError(t, tree.Remove(4))
+		require.Equal(t, []int{1, 4}, tree.Tombstones())
+		require.NoError(t, tree.Update(1, Element{42}))
+		require.Equal(t, []int{4}, tree.Tombstones())
+	})
+
+	t.Run("should skip removed leaves when asked to", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {0}}, Element{0}, SHA256Hash, WithSkipRemoved())
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(2))
+		require.Equal(t, []Element{{1}, {2}, {0}, {0}}, tree.Elements())
+		require.Equal(t, []LiveElement{{0, Element{1}}, {1, Element{2}}, {3, Element{0}}}, tree.LiveElements())
+		require.Equal(t, -1, tree.IndexOf(Element{3}))
+		require.Equal(t, 3, tree.IndexOf(Element{0}))
+		proof, err := tree.Proof(Element{0})
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 1, 0, 0, 0, 0, 0, 0, 0, 0}, proof.PathIndices)
+
+		plain, err := NewMerkleTree(10, []Element{{1}, {2}, {3}, {0}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, plain.Remove(2))
+		require.Equal(t, 2, plain.IndexOf(Element{0}))
+	})
+
+	t.Run("should survive serialization", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}, {2}, {3}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.NoError(t, tree.Remove(0))
+		require.NoError(t, tree.Remove(2))
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, SHA256Hash, WithSkipRemoved())
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 2}, tree2.Tombstones())
+		require.Equal(t, []Element{{0}, {2}, {0}}, tree2.Elements())
+		require.Equal(t, []LiveElement{{1, Element{2}}}, tree2.LiveElements())
+		require.Equal(t, tree.LiveElements(), tree2.LiveElements())
+	})
+}
@@ -0,0 +1,132 @@
+package fMerkleTree
+
+import (
+	"fmt"
+)
+
+type RootHistoryEntry struct {
+	Root Element `json:"root"`
+	Size int     `json:"size"`
+}
+
+// rootHistory is a ring buffer of the last roots, like ROOT_HISTORY_SIZE in MerkleTreeWithHistory.sol
+type rootHistory struct {
+	entries []RootHistoryEntry
+	next    int
+	count   int
+}
+
+func newRootHistory(size int) *rootHistory {
+	return &rootHistory{entries: make([]RootHistoryEntry, size)}
+}
+
+func (h *rootHistory) push(entry RootHistoryEntry) {
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.count < len(h.entries) {
+		h.count++
+	}
+}
+
+// list returns the recorded entries, oldest first
+func (h *rootHistory) list() []RootHistoryEntry {
+	out := make([]RootHistoryEntry, 0, h.count)
+	start := (h.next - h.count + len(h.entries)) % len(h.entries)
+	for i := 0; i < h.count; i++ {
+		out = append(out, h.entries[(start+i)%len(h.entries)])
+	}
+	return out
+}
+
+/**
+* Keep the last size roots of the tree
+* Every Insert, BulkInsert and Update records the new root
+* @param size Number of roots to keep
+ */
+func WithRootHistory(size int) TreeOption {
+	return func(bt *BaseTree) error {
+		if size < 1 {
+			return fmt.Errorf("invalid root history size: %d", size)
+		}
+		bt.history = newRootHistory(size)
+		return nil
+	}
+}
+
+func (bt *BaseTree) recordRoot() {
+	if bt.history == nil {
+		return
+	}
+	bt.history.push(RootHistoryEntry{Root: bt.root(), Size: bt.layerLen(0)})
+}
+
+// RootHistorySize returns the capacity of the root history, 0 if it is disabled
+func (bt BaseTree) RootHistorySize() int {
+	if bt.history == nil {
+		return 0
+	}
+	return len(bt.history.entries)
+}
+
+// RootHistory returns the recorded roots, oldest first
+func (bt BaseTree) RootHistory() []RootHistoryEntry {
+	if bt.history == nil {
+		return nil
+	}
+	return bt.history.list()
+}
+
+/**
+* Check whether root is one of the recorded roots
+* Without a root history only the current root is known. Pending deferred writes are committed first,
+* false is returned if they cannot be hashed
+ */
+func (bt *BaseTree) IsKnownRoot(root Element) bool {
+	if len(root) == 0 {
+		return false
+	}
+	if err := bt.Commit(); err != nil {
+		return false
+	}
+	if bt.history == nil {
+		return bt.root().Cmp(root)
+	}
+	for _, entry := range bt.history.entries[:bt.history.count] {
+		if entry.Root.Cmp(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastRoot commits pending deferred writes and returns the most recently recorded root, nil if they cannot be hashed
+func (bt *BaseTree) LastRoot() Element {
+	if err := bt.Commit(); err != nil {
+		return nil
+	}
+	if bt.history == nil || bt.history.count == 0 {
+		return bt.root()
+	}
+	return bt.history.entries[(bt.history.next-1+len(bt.history.entries))%len(bt.history.entries)].Root
+}
+
+/**
+* Get the latest recorded root of the tree when it held size elements
+* @param size Number of elements
+ */
+func (bt *BaseTree) RootAt(size int) (Element, error) {
+	if err := bt.Commit(); err != nil {
+		return nil, err
+	}
+	if bt.history != nil {
+		entries := bt.history.list()
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Size == size {
+				return entries[i].Root, nil
+			}
+		}
+	} else if size == bt.layerLen(0) {
+		return bt.root(), nil
+	}
+	return nil, fmt.Errorf("no root recorded for size %d", size)
+}
@@ -0,0 +1,93 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RootHistory(t *testing.T) {
+	t.Run("should keep the last roots", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}}, Element{0}, SHA256Hash, WithRootHistory(3))
+		require.NoError(t, err)
+		roots := []Element{tree.Root()}
+		require.NoError(t, tree.Insert(Element{2}))
+		roots = append(roots, tree.Root())
+		require.NoError(t, tree.Update(0, Element{3}))
+		roots = append(roots, tree.Root())
+		require.True(t, tree.IsKnownRoot(roots[0]))
+
+		require.NoError(t, tree.BulkInsert([]Element{{4}, {5}}))
+		roots = append(roots, tree.Root())
+		require.False(t, tree.IsKnownRoot(roots[0]))
+		require.False(t, tree.IsKnownRoot(roots[1]))
+		require.True(t, tree.IsKnownRoot(roots[2]))
+		require.True(t, tree.IsKnownRoot(tree.Root()))
+		require.False(t, tree.IsKnownRoot(nil))
+		require.Equal(t, tree.Root(), tree.LastRoot())
+		require.Len(t, tree.RootHistory(), 3)
+
+		root, err := tree.RootAt(2)
+		require.NoError(t, err)
+		require.Equal(t, roots[2], root)
+		_, err = tree.RootAt(1)
+		require.Error(t, err)
+	})
+
+	t.Run("should only know the current root when disabled", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{{1}}, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		old := tree.Root()
+		require.NoError(t, tree.Insert(Element{2}))
+		require.False(t, tree.IsKnownRoot(old))
+		require.True(t, tree.IsKnownRoot(tree.Root()))
+		require.Equal(t, 0, tree.RootHistorySize())
+		_, err = NewMerkleTree(10, nil, Element{0}, SHA256Hash, WithRootHistory(0))
+		require.Error(t, err)
+	})
+
+	t.Run("should survive serialization", func(t *testing.T) {
+		tree, err := NewMerkleTree(10, []Element{}, Element{0}, SHA256Hash, WithRootHistory(4))
+		require.NoError(t, err)
+		require.NoError(t, tree.BulkInsert([]Element{{1}, {2}, {3}, {4}, {5}}))
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, tree.RootHistory(), tree2.RootHistory())
+		require.NoError(t, tree.Insert(Element{6}))
+		require.NoError(t, tree2.Insert(Element{6}))
+		require.Equal(t, tree.RootHistory(), tree2.RootHistory())
+		root, err := tree2.RootAt(3)
+		require.NoError(t, err)
+		require.True(t, tree2.IsKnownRoot(root))
+	})
+
+	t.Run("should record every root of a bulk insert", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{nil, {WithFixedWidth()}, {WithHashDomain(RFC6962)}} {
+			tree, err := NewMerkleTree(4, newTestElements(0, 3), Element{0}, SHA256Hash, append(opts, WithRootHistory(10))...)
+			require.NoError(t, err)
+			expected, err := NewMerkleTree(4, newTestElements(0, 3), Element{0}, SHA256Hash, append(opts, WithRootHistory(10))...)
+			require.NoError(t, err)
+			for _, element := range newTestElements(3, 9) {
+				require.NoError(t, expected.Insert(element))
+			}
+			require.NoError(t, tree.BulkInsert(newTestElements(3, 9)))
+			require.Equal(t, expected.RootHistory(), tree.RootHistory())
+			require.Equal(t, expected.Layers(), tree.Layers())
+			require.Equal(t, expected.Elements(), tree.Elements())
+		}
+	})
+
+	t.Run("should leave the tree unchanged when a bulk insert fails", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, newTestElements(0, 3), Element{0}, failingHasher{SHA256Hasher, Element{9}}, WithRootHistory(10))
+		require.NoError(t, err)
+		root, elements, history := tree.Root(), tree.Elements(), tree.RootHistory()
+		require.Error(t, tree.BulkInsert([]Element{{7}, {9}, {8}}))
+		require.Equal(t, root, tree.Root())
+		require.Equal(t, elements, tree.Elements())
+		require.Equal(t, history, tree.RootHistory())
+		require.Error(t, tree.BulkInsert(newTestElements(0, 14)))
+		require.Equal(t, history, tree.RootHistory())
+	})
+}
@@ -0,0 +1,58 @@
+package fMerkleTree
+
+import (
+	"fmt"
+)
+
+// SortedPairHash wraps hashFn so that each pair is sorted before hashing, as OpenZeppelin's MerkleProof does
+func SortedPairHash(hashFn HashFunction) HashFunction {
+	return func(left Element, right Element) []byte {
+		if left.BigInt().Cmp(right.BigInt()) > 0 {
+			left, right = right, left
+		}
+		return hashFn(left, right)
+	}
+}
+
+/**
+* Hash sorted pairs everywhere in the tree
+* Paths can then be verified by OpenZeppelin's MerkleProof.verify with PathElements only
+ */
+func WithSortedPairs() TreeOption {
+	return func(bt *BaseTree) error {
+		if bt.hasher == nil {
+			return fmt.Errorf("hash function is nil")
+		}
+		if !bt.sortedPairs {
+			bt.sortedPairs = true
+			bt.setHasher(SortedPairHasher(bt.hasher))
+		}
+		return nil
+	}
+}
+
+func (bt BaseTree) SortedPairs() bool {
+	return bt.sortedPairs
+}
+
+/**
+* Verify a proof the way OpenZeppelin's MerkleProof.verify does, ignoring positions
+* @param leaf Leaf value the proof was generated for
+* @param proof Siblings from the leaf to the root, e.g. ProofPath.PathElements
+* @param root Trusted root
+* @param hashFn Unsorted hash function, e.g. Keccak256
+ */
+func VerifySortedProof(leaf Element, proof []Element, root Element, hashFn HashFunction) error {
+	if hashFn == nil {
+		return fmt.Errorf("hash function is nil")
+	}
+	sorted := SortedPairHash(hashFn)
+	node := leaf
+	for _, sibling := range proof {
+		node = sorted(node, sibling)
+	}
+	if !node.Cmp(root) {
+		return fmt.Errorf("invalid proof: root mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package fMerkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Keccak256(t *testing.T) {
+	// keccak256(bytes32(0), bytes32(0)), the first zero hash of the eth2 deposit contract
+	require.Equal(t, "ad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5", Element(Keccak256(Element{0}, Element{0})).Hex())
+}
+
+func Test_SortedPairs(t *testing.T) {
+	leaves := make([]Element, 0, 5)
+	for i := 1; i <= 5; i++ {
+		leaves = append(leaves, Keccak256(Element{byte(i)}, Element{byte(i)}))
+	}
+
+	t.Run("should hash sorted pairs", func(t *testing.T) {
+		tree, err := NewMerkleTree(1, leaves[:2], Element{0}, Keccak256, WithSortedPairs())
+		require.NoError(t, err)
+		require.True(t, tree.SortedPairs())
+		swapped, err := NewMerkleTree(1, []Element{leaves[1], leaves[0]}, Element{0}, Keccak256, WithSortedPairs())
+		require.NoError(t, err)
+		require.Equal(t, tree.Root(), swapped.Root())
+
+		low, high := leaves[0], leaves[1]
+		if low.BigInt().Cmp(high.BigInt()) > 0 {
+			low, high = high, low
+		}
+		require.Equal(t, Element(Keccak256(low, high)), tree.Root())
+	})
+
+	t.Run("should verify paths without indices", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, leaves, Element{0}, Keccak256, WithSortedPairs())
+		require.NoError(t, err)
+		for i, leaf := range leaves {
+			path, err := tree.Path(i)
+			require.NoError(t, err)
+			require.NoError(t, VerifySortedProof(leaf, path.PathElements, tree.Root(), Keccak256))
+			require.Error(t, VerifySortedProof(leaves[(i+1)%len(leaves)], path.PathElements, tree.Root(), Keccak256))
+		}
+	})
+
+	t.Run("should survive serialization", func(t *testing.T) {
+		tree, err := NewMerkleTree(8, leaves[:3], Element{0}, Keccak256, WithSortedPairs())
+		require.NoError(t, err)
+		data, err := tree.Serialize()
+		require.NoError(t, err)
+		tree2, err := DeserializeMerkleTree(data, Keccak256)
+		require.NoError(t, err)
+		require.True(t, tree2.SortedPairs())
+		require.NoError(t, tree.BulkInsert(leaves[3:]))
+		require.NoError(t, tree2.BulkInsert(leaves[3:]))
+		require.Equal(t, tree.Root(), tree2.Root())
+	})
+}
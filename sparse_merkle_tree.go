@@ -0,0 +1,235 @@
+package fMerkleTree
+
+import (
+	"fmt"
+	"math/big"
+)
+
+type sparseNodeKey struct {
+	level int
+	index string
+}
+
+/**
+* Sparse merkle tree addressed by keys of up to 256 bits.
+* Bit i of the key selects the side at level i, like the leaf index of the dense tree.
+* Only non-empty nodes are stored, every other node is the zero subtree of its level.
+ */
+type SparseMerkleTree struct {
+	levels      int
+	hasher      Hasher
+	zeroElement Element
+	zeros       []Element
+	nodes       map[sparseNodeKey]Element
+	size        int
+}
+
+/**
+* Merkle path of a key in a sparse merkle tree.
+* When Exists is false the path proves that the leaf at Key is the zero element.
+ */
+type SparseMerkleProof struct {
+	ProofPath
+	Key    *big.Int `json:"key"`
+	Value  Element  `json:"value"`
+	Exists bool     `json:"exists"`
+}
+
+func NewSparseMerkleTree(levels int, zeroElement Element, hashFn HashFunction) (*SparseMerkleTree, error) {
+	if levels < 1 || levels > 256 {
+		return nil, fmt.Errorf("invalid levels: %d", levels)
+	}
+	if hashFn == nil {
+		return nil, fmt.Errorf("hash function is nil")
+	}
+	out := &SparseMerkleTree{
+		levels:      levels,
+		hasher:      hasherOf(hashFn),
+		zeroElement: zeroElement,
+		zeros:       make([]Element, levels+1),
+		nodes:       make(map[sparseNodeKey]Element),
+	}
+	out.zeros[0] = zeroElement
+	for level := 1; level <= levels; level++ {
+		zero, err := out.hasher.Hash(out.zeros[level-1], out.zeros[level-1])
+		if err != nil {
+			return nil, err
+		}
+		out.zeros[level] = zero
+	}
+	return out, nil
+}
+
+func (st SparseMerkleTree) Levels() int {
+	return st.levels
+}
+
+func (st SparseMerkleTree) Zeros() []Element {
+	return st.zeros
+}
+
+// Size returns the number of non-empty leaves
+func (st SparseMerkleTree) Size() int {
+	return st.size
+}
+
+func (st SparseMerkleTree) Root() Element {
+	return st.node(st.levels, new(big.Int))
+}
+
+func (st SparseMerkleTree) node(level int, index *big.Int) Element {
+	if node, ok := st.nodes[sparseNodeKey{level, string(index.Bytes())}]; ok {
+		return node
+	}
+	return st.zeros[level]
+}
+
+func (st SparseMerkleTree) checkKey(key *big.Int) error {
+	if key == nil || key.Sign() < 0 || key.BitLen() > st.levels {
+		return fmt.Errorf("key out of bounds: %v", key)
+	}
+	return nil
+}
+
+/**
+* Get the value stored at a key
+* @returns the value and true if the key is set, otherwise the zero element and false
+ */
+func (st SparseMerkleTree) Get(key *big.Int) (Element, bool) {
+	if st.checkKey(key) != nil {
+		return st.zeroElement, false
+	}
+	node, ok := st.nodes[sparseNodeKey{0, string(key.Bytes())}]
+	if !ok {
+		return st.zeroElement, false
+	}
+	return node, true
+}
+
+/**
+* Set the value of a key
+* Setting a key to the zero element is the same as deleting it.
+* The whole path is hashed before the tree is changed, a value the hash function rejects leaves it unchanged
+ */
+func (st *SparseMerkleTree) Set(key *big.Int, value Element) error {
+	if err := st.checkKey(key); err != nil {
+		return err
+	}
+	keys := make([]sparseNodeKey, st.levels+1)
+	path := make([]Element, st.levels+1)
+	index := new(big.Int).Set(key)
+	node := value
+	for level := 0; ; level++ {
+		keys[level] = sparseNodeKey{level, string(index.Bytes())}
+		path[level] = node
+		if level == st.levels {
+			break
+		}
+		sibling := st.node(level, siblingIndex(index))
+		var err error
+		if index.Bit(0) == 0 {
+			node, err = st.hasher.Hash(node, sibling)
+		} else {
+			node, err = st.hasher.Hash(sibling, node)
+		}
+		if err != nil {
+			return err
+		}
+		index.Rsh(index, 1)
+	}
+
+	_, existed := st.Get(key)
+	exists := !value.Cmp(st.zeroElement)
+	switch {
+	case exists && !existed:
+		st.size++
+	case !exists && existed:
+		st.size--
+	}
+	for level, node := range path {
+		if node.Cmp(st.zeros[level]) {
+			delete(st.nodes, keys[level])
+		} else {
+			st.nodes[keys[level]] = node
+		}
+	}
+	return nil
+}
+
+func (st *SparseMerkleTree) Delete(key *big.Int) error {
+	return st.Set(key, st.zeroElement)
+}
+
+/**
+* Get a membership or non-membership proof for a key
+* @param key Key to prove
+ */
+func (st SparseMerkleTree) Proof(key *big.Int) (SparseMerkleProof, error) {
+	if err := st.checkKey(key); err != nil {
+		return SparseMerkleProof{}, err
+	}
+	value, exists := st.Get(key)
+	proof := SparseMerkleProof{
+		ProofPath: ProofPath{
+			PathElements:  make([]Element, st.levels),
+			PathIndices:   make([]int, st.levels),
+			PathPositions: make([]int, st.levels),
+			PathRoot:      st.Root(),
+		},
+		Key:    new(big.Int).Set(key),
+		Value:  value,
+		Exists: exists,
+	}
+	index := new(big.Int).Set(key)
+	for level := 0; level < st.levels; level++ {
+		proof.PathIndices[level] = int(index.Bit(0))
+		proof.PathElements[level] = st.node(level, siblingIndex(index))
+		index.Rsh(index, 1)
+	}
+	return proof, nil
+}
+
+func siblingIndex(index *big.Int) *big.Int {
+	return new(big.Int).SetBit(index, 0, index.Bit(0)^1)
+}
+
+/**
+* Verify a sparse merkle proof against a trusted root
+* @param root Trusted root
+* @param zeroElement Value of empty leaves, used for non-membership proofs
+* @param hashFn Hash function the tree was built with
+ */
+func (p SparseMerkleProof) Verify(root Element, zeroElement Element, hashFn HashFunction) error {
+	if p.Key == nil || p.Key.Sign() < 0 || p.Key.BitLen() > len(p.PathIndices) {
+		return fmt.Errorf("invalid proof: key out of bounds")
+	}
+	for level := range p.PathIndices {
+		if p.PathIndices[level] != int(p.Key.Bit(level)) {
+			return fmt.Errorf("invalid proof: path does not match key")
+		}
+	}
+	leaf := zeroElement
+	if p.Exists {
+		if p.Value.Cmp(zeroElement) {
+			return fmt.Errorf("invalid proof: member has the zero value")
+		}
+		leaf = p.Value
+	}
+	return p.ProofPath.Verify(leaf, root, hashFn)
+}
+
+// VerifySparseMembership checks that key holds value under root
+func VerifySparseMembership(key *big.Int, value Element, proof SparseMerkleProof, root Element, zeroElement Element, hashFn HashFunction) error {
+	if !proof.Exists || proof.Key == nil || proof.Key.Cmp(key) != 0 || !proof.Value.Cmp(value) {
+		return fmt.Errorf("invalid proof: not a membership proof for key %v", key)
+	}
+	return proof.Verify(root, zeroElement, hashFn)
+}
+
+// VerifySparseNonMembership checks that key is not set under root
+func VerifySparseNonMembership(key *big.Int, proof SparseMerkleProof, root Element, zeroElement Element, hashFn HashFunction) error {
+	if proof.Exists || proof.Key == nil || proof.Key.Cmp(key) != 0 {
+		return fmt.Errorf("invalid proof: not a non-membership proof for key %v", key)
+	}
+	return proof.Verify(root, zeroElement, hashFn)
+}
@@ -0,0 +1,106 @@
+package fMerkleTree
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SparseMerkleTree(t *testing.T) {
+	t.Run("should match the dense tree for left-packed keys", func(t *testing.T) {
+		elements := []Element{{1}, {2}, {3}, {4}, {5}}
+		tree, err := NewMerkleTree(10, elements, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		sparse, err := NewSparseMerkleTree(10, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Equal(t, tree.Zeros()[10], sparse.Root())
+		for i, element := range elements {
+			require.NoError(t, sparse.Set(big.NewInt(int64(i)), element))
+		}
+		require.Equal(t, tree.Root(), sparse.Root())
+		require.Equal(t, 5, sparse.Size())
+
+		proof, err := sparse.Proof(big.NewInt(3))
+		require.NoError(t, err)
+		path, err := tree.Path(3)
+		require.NoError(t, err)
+		require.Equal(t, path.PathElements, proof.PathElements)
+		require.Equal(t, path.PathIndices, proof.PathIndices)
+	})
+
+	t.Run("should prove membership and non-membership of 256 bit keys", func(t *testing.T) {
+		sparse, err := NewSparseMerkleTree(256, Element{0}, Poseidon)
+		require.NoError(t, err)
+		key, _ := new(big.Int).SetString("f0000000000000000000000000000000000000000000000000000000000000ff", 16)
+		absent, _ := new(big.Int).SetString("f0000000000000000000000000000000000000000000000000000000000000fe", 16)
+		require.NoError(t, sparse.Set(key, Element{42}))
+		require.NoError(t, sparse.Set(big.NewInt(7), Element{43}))
+
+		value, ok := sparse.Get(key)
+		require.True(t, ok)
+		require.Equal(t, Element{42}, value)
+
+		proof, err := sparse.Proof(key)
+		require.NoError(t, err)
+		require.NoError(t, VerifySparseMembership(key, Element{42}, proof, sparse.Root(), Element{0}, Poseidon))
+		require.Error(t, VerifySparseNonMembership(key, proof, sparse.Root(), Element{0}, Poseidon))
+		require.Error(t, VerifySparseMembership(key, Element{41}, proof, sparse.Root(), Element{0}, Poseidon))
+
+		proof, err = sparse.Proof(absent)
+		require.NoError(t, err)
+		require.False(t, proof.Exists)
+		require.NoError(t, VerifySparseNonMembership(absent, proof, sparse.Root(), Element{0}, Poseidon))
+
+		// a non-membership proof for a key that is set must not verify
+		forged, err := sparse.Proof(key)
+		require.NoError(t, err)
+		forged.Exists = false
+		require.Error(t, VerifySparseNonMembership(key, forged, sparse.Root(), Element{0}, Poseidon))
+	})
+
+	t.Run("should delete keys and drop empty nodes", func(t *testing.T) {
+		sparse, err := NewSparseMerkleTree(32, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		empty := sparse.Root()
+		require.NoError(t, sparse.Set(big.NewInt(1000), Element{1}))
+		require.NoError(t, sparse.Set(big.NewInt(5), Element{2}))
+		require.NoError(t, sparse.Delete(big.NewInt(1000)))
+		require.NoError(t, sparse.Delete(big.NewInt(5)))
+		require.Equal(t, empty, sparse.Root())
+		require.Empty(t, sparse.nodes)
+		require.Equal(t, 0, sparse.Size())
+		_, ok := sparse.Get(big.NewInt(5))
+		require.False(t, ok)
+	})
+
+	t.Run("should reject keys out of range", func(t *testing.T) {
+		sparse, err := NewSparseMerkleTree(8, Element{0}, SHA256Hash)
+		require.NoError(t, err)
+		require.Error(t, sparse.Set(big.NewInt(256), Element{1}))
+		require.Error(t, sparse.Set(big.NewInt(-1), Element{1}))
+		_, err = sparse.Proof(big.NewInt(256))
+		require.Error(t, err)
+		_, err = NewSparseMerkleTree(257, Element{0}, SHA256Hash)
+		require.Error(t, err)
+	})
+
+	t.Run("should reject values outside the field", func(t *testing.T) {
+		sparse, err := NewSparseMerkleTree(16, Element{0}, Poseidon)
+		require.NoError(t, err)
+		require.NoError(t, sparse.Set(big.NewInt(3), Element{1}))
+		root := sparse.Root()
+		outside := Element(new(big.Int).Add(BN254ScalarField, big.NewInt(5)).Bytes())
+		require.Error(t, sparse.Set(big.NewInt(5), outside))
+		require.Error(t, sparse.Set(big.NewInt(3), outside))
+		require.Equal(t, root, sparse.Root())
+		require.Equal(t, 1, sparse.Size())
+		value, ok := sparse.Get(big.NewInt(3))
+		require.True(t, ok)
+		require.Equal(t, Element{1}, value)
+		_, ok = sparse.Get(big.NewInt(5))
+		require.False(t, ok)
+		_, err = NewSparseMerkleTree(16, outside, Poseidon)
+		require.Error(t, err)
+	})
+}
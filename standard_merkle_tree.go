@@ -0,0 +1,379 @@
+package fMerkleTree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/0xbow-io/go-iden3-crypto/keccak256"
+)
+
+const standardTreeFormat = "standard-v1"
+
+type StandardValue struct {
+	Value     []any `json:"value"`
+	TreeIndex int   `json:"treeIndex"`
+}
+
+type standardTreeData struct {
+	Format       string          `json:"format"`
+	Tree         []string        `json:"tree"`
+	Values       []StandardValue `json:"values"`
+	LeafEncoding []string        `json:"leafEncoding"`
+}
+
+/**
+* Go counterpart of StandardMerkleTree from @openzeppelin/merkle-tree.
+* Leaves are keccak256(keccak256(abi.encode(value))), nodes hash sorted pairs with keccak256
+* and the tree is stored as a flat array with the root at 0 and the children of i at 2i+1 and 2i+2.
+ */
+type StandardMerkleTree struct {
+	tree         []Element
+	values       []StandardValue
+	leafEncoding []string
+}
+
+/**
+* Build a tree from typed values, leaves are sorted by hash like StandardMerkleTree.of
+* @param values Values to include, one entry per leafEncoding type each
+* @param leafEncoding ABI types of a value, e.g. ["address", "uint256"]
+ */
+func NewStandardMerkleTree(values [][]any, leafEncoding []string) (*StandardMerkleTree, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("expected non-zero number of leaves")
+	}
+	type hashedValue struct {
+		valueIndex int
+		hash       Element
+	}
+	hashed := make([]hashedValue, len(values))
+	for i, value := range values {
+		hash, err := StandardLeafHash(leafEncoding, value)
+		if err != nil {
+			return nil, err
+		}
+		hashed[i] = hashedValue{valueIndex: i, hash: hash}
+	}
+	sort.SliceStable(hashed, func(i, j int) bool {
+		return bytes.Compare(hashed[i].hash, hashed[j].hash) < 0
+	})
+
+	leaves := make([]Element, len(hashed))
+	for i, h := range hashed {
+		leaves[i] = h.hash
+	}
+	out := &StandardMerkleTree{
+		tree:         makeStandardTree(leaves),
+		values:       make([]StandardValue, len(values)),
+		leafEncoding: leafEncoding,
+	}
+	for leafIndex, h := range hashed {
+		out.values[h.valueIndex] = StandardValue{Value: values[h.valueIndex], TreeIndex: len(out.tree) - leafIndex - 1}
+	}
+	return out, nil
+}
+
+/**
+* Load a tree dumped by StandardMerkleTree.dump() or StandardMerkleTree.Dump
+* The tree and every value are validated
+ */
+func LoadStandardMerkleTree(data []byte) (*StandardMerkleTree, error) {
+	var dump standardTreeData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&dump); err != nil {
+		return nil, err
+	}
+	if dump.Format != standardTreeFormat {
+		return nil, fmt.Errorf("unknown format: %q", dump.Format)
+	}
+	out := &StandardMerkleTree{
+		tree:         make([]Element, len(dump.Tree)),
+		values:       dump.Values,
+		leafEncoding: dump.LeafEncoding,
+	}
+	for i, node := range dump.Tree {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(node, "0x"))
+		if err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("invalid node at %d: %q", i, node)
+		}
+		out.tree[i] = decoded
+	}
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Dump returns the tree in the JSON format of StandardMerkleTree.dump()
+func (st StandardMerkleTree) Dump() ([]byte, error) {
+	dump := standardTreeData{
+		Format:       standardTreeFormat,
+		Tree:         make([]string, len(st.tree)),
+		Values:       st.values,
+		LeafEncoding: st.leafEncoding,
+	}
+	for i, node := range st.tree {
+		dump.Tree[i] = "0x" + node.Hex()
+	}
+	return json.Marshal(dump)
+}
+
+func (st StandardMerkleTree) Root() Element {
+	return st.tree[0]
+}
+
+func (st StandardMerkleTree) LeafEncoding() []string {
+	return st.leafEncoding
+}
+
+func (st StandardMerkleTree) Values() []StandardValue {
+	return st.values
+}
+
+// Validate checks every internal node and the leaf hash of every value
+func (st StandardMerkleTree) Validate() error {
+	if len(st.tree) == 0 || len(st.tree)%2 == 0 {
+		return fmt.Errorf("invalid tree length: %d", len(st.tree))
+	}
+	for i := len(st.tree)/2 - 1; i >= 0; i-- {
+		if !st.tree[i].Cmp(standardNodeHash(st.tree[2*i+1], st.tree[2*i+2])) {
+			return fmt.Errorf("invalid node at %d", i)
+		}
+	}
+	for i, value := range st.values {
+		if value.TreeIndex < len(st.tree)/2 || value.TreeIndex >= len(st.tree) {
+			return fmt.Errorf("value %d is not at a leaf", i)
+		}
+		hash, err := StandardLeafHash(st.leafEncoding, value.Value)
+		if err != nil {
+			return err
+		}
+		if !hash.Cmp(st.tree[value.TreeIndex]) {
+			return fmt.Errorf("leaf hash mismatch for value %d", i)
+		}
+	}
+	return nil
+}
+
+/**
+* Get the proof of a value
+* PathElements is the proof returned by StandardMerkleTree.getProof, PathIndices and PathPositions
+* describe the node positions in the flat tree and are not needed by MerkleProof.verify
+* @param index Index of the value, in the order the values were given
+ */
+func (st StandardMerkleTree) GetProof(index int) (ProofPath, error) {
+	if index < 0 || index >= len(st.values) {
+		return ProofPath{}, fmt.Errorf("index out of bounds: %d", index)
+	}
+	path := ProofPath{PathRoot: st.Root()}
+	for node := st.values[index].TreeIndex; node > 0; node = (node - 1) / 2 {
+		sibling := node + 1
+		position := 0
+		if node%2 == 0 {
+			sibling = node - 1
+			position = 1
+		}
+		path.PathElements = append(path.PathElements, st.tree[sibling])
+		path.PathIndices = append(path.PathIndices, position)
+		path.PathPositions = append(path.PathPositions, sibling)
+	}
+	return path, nil
+}
+
+// LeafHash returns the leaf hash of a value encoded with the tree's leaf encoding
+func (st StandardMerkleTree) LeafHash(value []any) (Element, error) {
+	return StandardLeafHash(st.leafEncoding, value)
+}
+
+/**
+* Verify a StandardMerkleTree proof like MerkleProof.verify
+* @param root Trusted root
+* @param leafEncoding ABI types of the value
+* @param value Proven value
+* @param proof Proof elements, e.g. ProofPath.PathElements
+ */
+func VerifyStandardProof(root Element, leafEncoding []string, value []any, proof []Element) error {
+	leaf, err := StandardLeafHash(leafEncoding, value)
+	if err != nil {
+		return err
+	}
+	return VerifySortedProof(leaf, proof, root, Keccak256)
+}
+
+func makeStandardTree(leaves []Element) []Element {
+	tree := make([]Element, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		tree[i] = standardNodeHash(tree[2*i+1], tree[2*i+2])
+	}
+	return tree
+}
+
+func standardNodeHash(left, right Element) Element {
+	if bytes.Compare(left, right) > 0 {
+		left, right = right, left
+	}
+	return keccak256.Hash(left, right)
+}
+
+// StandardLeafHash returns keccak256(keccak256(abi.encode(value))) for the given ABI types
+func StandardLeafHash(leafEncoding []string, value []any) (Element, error) {
+	encoded, err := abiEncode(leafEncoding, value)
+	if err != nil {
+		return nil, err
+	}
+	return keccak256.Hash(keccak256.Hash(encoded)), nil
+}
+
+// abiEncode implements abi.encode for a tuple of elementary types, string and bytes
+func abiEncode(types []string, values []any) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(types), len(values))
+	}
+	var head, tail []byte
+	for i, typ := range types {
+		if typ == "string" || typ == "bytes" {
+			data, err := abiDynamicBytes(typ, values[i])
+			if err != nil {
+				return nil, err
+			}
+			head = append(head, abiWord(big.NewInt(int64(32*len(types)+len(tail))))...)
+			tail = append(tail, abiWord(big.NewInt(int64(len(data))))...)
+			tail = append(tail, data...)
+			if pad := len(data) % 32; pad != 0 {
+				tail = append(tail, make([]byte, 32-pad)...)
+			}
+			continue
+		}
+		word, err := abiStaticWord(typ, values[i])
+		if err != nil {
+			return nil, err
+		}
+		head = append(head, word...)
+	}
+	return append(head, tail...), nil
+}
+
+func abiDynamicBytes(typ string, value any) ([]byte, error) {
+	if typ == "string" {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value: %v", value)
+		}
+		return []byte(s), nil
+	}
+	return abiBytesValue(value)
+}
+
+func abiStaticWord(typ string, value any) ([]byte, error) {
+	switch {
+	case typ == "address":
+		data, err := abiBytesValue(value)
+		if err != nil || len(data) != 20 {
+			return nil, fmt.Errorf("invalid address value: %v", value)
+		}
+		return append(make([]byte, 12), data...), nil
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value: %v", value)
+		}
+		if b {
+			return abiWord(big.NewInt(1)), nil
+		}
+		return abiWord(big.NewInt(0)), nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		size, err := abiTypeSize(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"), 256)
+		if err != nil || size%8 != 0 || size < 8 || size > 256 {
+			return nil, fmt.Errorf("unsupported type: %s", typ)
+		}
+		n, err := abiIntValue(value)
+		if err != nil {
+			return nil, err
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(size))
+		if signed {
+			limit.Rsh(limit, 1)
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("value out of range for %s: %v", typ, n)
+			}
+			if n.Sign() < 0 {
+				n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+		} else if n.Sign() < 0 || n.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("value out of range for %s: %v", typ, n)
+		}
+		return abiWord(n), nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := abiTypeSize(strings.TrimPrefix(typ, "bytes"), 0)
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unsupported type: %s", typ)
+		}
+		data, err := abiBytesValue(value)
+		if err != nil || len(data) != size {
+			return nil, fmt.Errorf("invalid %s value: %v", typ, value)
+		}
+		return append(data, make([]byte, 32-size)...), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", typ)
+}
+
+func abiTypeSize(suffix string, fallback int) (int, error) {
+	if suffix == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(suffix)
+}
+
+func abiWord(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, 32))
+}
+
+func abiBytesValue(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case Element:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") {
+			return nil, fmt.Errorf("expected 0x-prefixed hex: %q", v)
+		}
+		return hex.DecodeString(v[2:])
+	}
+	return nil, fmt.Errorf("invalid bytes value: %v", value)
+}
+
+func abiIntValue(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case json.Number:
+		return abiIntValue(string(v))
+	case string:
+		n, ok := new(big.Int).SetString(v, 10)
+		if strings.HasPrefix(v, "0x") {
+			n, ok = new(big.Int).SetString(v[2:], 16)
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value: %q", v)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("invalid integer value: %v", value)
+}
@@ -0,0 +1,81 @@
+package fMerkleTree
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tree.json from the @openzeppelin/merkle-tree README
+const ozReadmeDump = `{"format":"standard-v1","tree":["0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77","0xeb02c421cfa48976e66dfb29120745909ea3a0f843456c263cf8f1253483e283","0xb92c48e9d7abe27fd8dfd6b5dfdbfb1c9a463f80c712b66f3a5180a090cccafc"],"values":[{"value":["0x1111111111111111111111111111111111111111","5000000000000000000"],"treeIndex":1},{"value":["0x2222222222222222222222222222222222222222","2500000000000000000"],"treeIndex":2}],"leafEncoding":["address","uint256"]}`
+
+func Test_StandardMerkleTree(t *testing.T) {
+	values := [][]any{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+		{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+	}
+
+	t.Run("should match the JS library", func(t *testing.T) {
+		tree, err := NewStandardMerkleTree(values, []string{"address", "uint256"})
+		require.NoError(t, err)
+		require.Equal(t, "d4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77", tree.Root().Hex())
+		proof, err := tree.GetProof(0)
+		require.NoError(t, err)
+		require.Len(t, proof.PathElements, 1)
+		require.Equal(t, "b92c48e9d7abe27fd8dfd6b5dfdbfb1c9a463f80c712b66f3a5180a090cccafc", proof.PathElements[0].Hex())
+
+		dump, err := tree.Dump()
+		require.NoError(t, err)
+		require.JSONEq(t, ozReadmeDump, string(dump))
+	})
+
+	t.Run("should load and verify a dump", func(t *testing.T) {
+		tree, err := LoadStandardMerkleTree([]byte(ozReadmeDump))
+		require.NoError(t, err)
+		for i, value := range tree.Values() {
+			proof, err := tree.GetProof(i)
+			require.NoError(t, err)
+			require.NoError(t, VerifyStandardProof(tree.Root(), tree.LeafEncoding(), value.Value, proof.PathElements))
+			require.NoError(t, proof.Verify(tree.tree[value.TreeIndex], tree.Root(), SortedPairHash(Keccak256)))
+		}
+		require.Error(t, VerifyStandardProof(tree.Root(), tree.LeafEncoding(), []any{"0x1111111111111111111111111111111111111111", "1"}, nil))
+
+		_, err = LoadStandardMerkleTree([]byte(strings.Replace(ozReadmeDump, "5000000000000000000", "5000000000000000001", 1)))
+		require.Error(t, err)
+		_, err = LoadStandardMerkleTree([]byte(strings.Replace(ozReadmeDump, "standard-v1", "standard-v2", 1)))
+		require.Error(t, err)
+	})
+
+	t.Run("should prove larger trees", func(t *testing.T) {
+		values := [][]any{}
+		for i := 0; i < 7; i++ {
+			values = append(values, []any{"0x" + strings.Repeat(hex.EncodeToString([]byte{byte(i)}), 20), i * 1000, i%2 == 0})
+		}
+		tree, err := NewStandardMerkleTree(values, []string{"address", "uint96", "bool"})
+		require.NoError(t, err)
+		require.NoError(t, tree.Validate())
+		for i, value := range values {
+			proof, err := tree.GetProof(i)
+			require.NoError(t, err)
+			require.NoError(t, VerifyStandardProof(tree.Root(), tree.LeafEncoding(), value, proof.PathElements))
+		}
+	})
+
+	t.Run("should abi encode dynamic and signed types", func(t *testing.T) {
+		encoded, err := abiEncode([]string{"string", "int8", "bytes2"}, []any{"hello", -1, "0xabcd"})
+		require.NoError(t, err)
+		expected := "0000000000000000000000000000000000000000000000000000000000000060" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" +
+			"abcd000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000005" +
+			"68656c6c6f000000000000000000000000000000000000000000000000000000"
+		require.Equal(t, expected, hex.EncodeToString(encoded))
+
+		_, err = abiEncode([]string{"uint8"}, []any{256})
+		require.Error(t, err)
+		_, err = abiEncode([]string{"address[]"}, []any{nil})
+		require.Error(t, err)
+	})
+}
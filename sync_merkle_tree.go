@@ -0,0 +1,177 @@
+package fMerkleTree
+
+import (
+	"sync"
+)
+
+/**
+* MerkleTree that is safe for concurrent use.
+* Writers hold an exclusive lock, Root, Path, Proof, IndexOf and GetTreeSlices hold a shared lock and run concurrently.
+* With deferred hashing a read first commits the pending writes under the exclusive lock.
+* Everything a read returns is a copy, so it is not changed by later writes.
+ */
+type SyncMerkleTree struct {
+	mu   sync.RWMutex
+	tree *MerkleTree
+}
+
+/**
+* Wrap a tree for concurrent use
+* @param tree Tree to wrap, it must not be used directly afterwards
+ */
+func NewSyncMerkleTree(tree *MerkleTree) *SyncMerkleTree {
+	return &SyncMerkleTree{tree: tree}
+}
+
+// rlock takes the read lock once no deferred write is pending, as reading a dirty tree would hash into it
+func (st *SyncMerkleTree) rlock() error {
+	st.mu.RLock()
+	for st.tree.Dirty() {
+		st.mu.RUnlock()
+		st.mu.Lock()
+		err := st.tree.Commit()
+		st.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		st.mu.RLock()
+	}
+	return nil
+}
+
+// Root commits pending writes and returns the root, it fails if they cannot be hashed
+func (st *SyncMerkleTree) Root() (Element, error) {
+	if err := st.rlock(); err != nil {
+		return nil, err
+	}
+	defer st.mu.RUnlock()
+	return st.tree.Root(), nil
+}
+
+// Path returns the path together with the root it leads to in PathRoot
+func (st *SyncMerkleTree) Path(index int) (ProofPath, error) {
+	if err := st.rlock(); err != nil {
+		return ProofPath{}, err
+	}
+	defer st.mu.RUnlock()
+	return st.tree.Path(index)
+}
+
+/**
+* Get the root and the path of a leaf from the same version of the tree
+* @param index Leaf index to generate path for
+ */
+func (st *SyncMerkleTree) RootAndPath(index int) (Element, ProofPath, error) {
+	path, err := st.Path(index)
+	if err != nil {
+		return nil, ProofPath{}, err
+	}
+	return path.PathRoot, path, nil
+}
+
+// Proof looks up element and gets its path under one lock
+func (st *SyncMerkleTree) Proof(element Element) (ProofPath, error) {
+	if err := st.rlock(); err != nil {
+		return ProofPath{}, err
+	}
+	defer st.mu.RUnlock()
+	return st.tree.Proof(element)
+}
+
+func (st *SyncMerkleTree) IndexOf(element Element) int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.IndexOf(element)
+}
+
+func (st *SyncMerkleTree) Elements() []Element {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return append([]Element{}, st.tree.Elements()...)
+}
+
+func (st *SyncMerkleTree) GetTreeSlices(count int) ([]TreeSlice, error) {
+	if err := st.rlock(); err != nil {
+		return nil, err
+	}
+	defer st.mu.RUnlock()
+	slices, err := st.tree.GetTreeSlices(count)
+	if err != nil {
+		return nil, err
+	}
+	for i := range slices {
+		slices[i].Elements = append([]Element{}, slices[i].Elements...)
+	}
+	return slices, nil
+}
+
+// IsKnownRoot returns false if pending writes cannot be hashed
+func (st *SyncMerkleTree) IsKnownRoot(root Element) bool {
+	if err := st.rlock(); err != nil {
+		return false
+	}
+	defer st.mu.RUnlock()
+	return st.tree.IsKnownRoot(root)
+}
+
+func (st *SyncMerkleTree) Serialize() (SerializedTreeState, error) {
+	if err := st.rlock(); err != nil {
+		return nil, err
+	}
+	defer st.mu.RUnlock()
+	return st.tree.Serialize()
+}
+
+/**
+* Run several reads on the same version of the tree
+* fn must not modify the tree or keep references to it after returning
+ */
+func (st *SyncMerkleTree) View(fn func(tree *MerkleTree) error) error {
+	if err := st.rlock(); err != nil {
+		return err
+	}
+	defer st.mu.RUnlock()
+	return fn(st.tree)
+}
+
+func (st *SyncMerkleTree) Insert(element Element) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Insert(element)
+}
+
+func (st *SyncMerkleTree) BulkInsert(elements []Element) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.BulkInsert(elements)
+}
+
+func (st *SyncMerkleTree) Append(elements []Element) (AppendResult, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Append(elements)
+}
+
+func (st *SyncMerkleTree) Update(index int, element Element) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Update(index, element)
+}
+
+func (st *SyncMerkleTree) BatchUpdate(updates map[int]Element) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.BatchUpdate(updates)
+}
+
+func (st *SyncMerkleTree) Remove(index int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Remove(index)
+}
+
+func (st *SyncMerkleTree) Commit() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Commit()
+}
@@ -0,0 +1,130 @@
+package fMerkleTree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SyncMerkleTree(t *testing.T) {
+	elements := newTestElements(0, 200)
+
+	t.Run("should serve reads while writing", func(t *testing.T) {
+		for _, opts := range [][]TreeOption{nil, {WithFixedWidth()}, {WithDeferredHashing()}, {WithHashDomain(RFC6962)}} {
+			tree, err := NewMerkleTree(8, elements[:10], Element{0}, SHA256Hash, append(opts, WithWorkers(2), WithRootHistory(300))...)
+			require.NoError(t, err)
+			hashFn := HashFunc(tree.Hasher())
+			leaves := make([]Element, 10)
+			for i := range leaves {
+				leaves[i], err = tree.leafNode(i, elements[i])
+				require.NoError(t, err)
+			}
+			st := NewSyncMerkleTree(tree)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 10; i < len(elements); i += 10 {
+					assert.NoError(t, st.BulkInsert(elements[i:i+5]))
+					for _, element := range elements[i+5 : i+10] {
+						assert.NoError(t, st.Insert(element))
+					}
+					assert.NoError(t, st.Update(i/2, elements[i/2]))
+				}
+			}()
+			for r := 0; r < 4; r++ {
+				wg.Add(1)
+				go func(r int) {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						index := (r*50 + i) % 10
+						root, path, err := st.RootAndPath(index)
+						assert.NoError(t, err)
+						assert.NoError(t, path.Verify(leaves[index], root, hashFn))
+						assert.True(t, st.IsKnownRoot(root))
+						assert.Equal(t, index, st.IndexOf(elements[index]))
+						root, err = st.Root()
+						assert.NoError(t, err)
+						assert.NotNil(t, root)
+
+						slices, err := st.GetTreeSlices(3)
+						assert.NoError(t, err)
+						assert.NotEmpty(t, slices)
+						assert.GreaterOrEqual(t, len(st.Elements()), 10)
+					}
+				}(r)
+			}
+			wg.Wait()
+
+			expected, err := NewMerkleTree(8, elements, Element{0}, SHA256Hash, opts...)
+			require.NoError(t, err)
+			root, err := st.Root()
+			require.NoError(t, err)
+			require.Equal(t, expected.Root(), root)
+			require.Equal(t, expected.Elements(), st.Elements())
+		}
+	})
+
+	t.Run("should return copies", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, elements[:4], Element{0}, SHA256Hash, WithFixedWidth())
+		require.NoError(t, err)
+		st := NewSyncMerkleTree(tree)
+		leaves := st.Elements()
+		slices, err := st.GetTreeSlices(2)
+		require.NoError(t, err)
+		require.NoError(t, st.Update(0, Element{9}))
+		require.NoError(t, st.Update(2, Element{9}))
+		require.Equal(t, elements[0], leaves[0])
+		require.Equal(t, elements[0], slices[0].Edge.EdgeElement)
+		require.Equal(t, elements[2], slices[1].Elements[0])
+	})
+
+	t.Run("should read a consistent version", func(t *testing.T) {
+		tree, err := NewMerkleTree(4, elements[:4], Element{0}, Poseidon, WithDeferredHashing())
+		require.NoError(t, err)
+		st := NewSyncMerkleTree(tree)
+		require.NoError(t, st.Insert(Element{9}))
+		require.NoError(t, st.Update(1, Element{8}))
+		expected, err := NewMerkleTree(4, []Element{elements[0], {8}, elements[2], elements[3], {9}}, Element{0}, Poseidon)
+		require.NoError(t, err)
+
+		root, path, err := st.RootAndPath(1)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), root)
+		require.NoError(t, path.Verify(Element{8}, root, Poseidon))
+		path, err = st.Proof(Element{9})
+		require.NoError(t, err)
+		require.Equal(t, root, path.PathRoot)
+
+		require.NoError(t, st.View(func(tree *MerkleTree) error {
+			require.False(t, tree.Dirty())
+			require.Equal(t, root, tree.Root())
+			return nil
+		}))
+		data, err := st.Serialize()
+		require.NoError(t, err)
+		require.Equal(t, root, data.GetRoot())
+	})
+
+	t.Run("should report failed commits", func(t *testing.T) {
+		tree, err := NewMerkleTreeWithHasher(4, elements[:4], Element{0}, failingHasher{SHA256Hasher, Element{9}}, WithDeferredHashing())
+		require.NoError(t, err)
+		st := NewSyncMerkleTree(tree)
+		require.NoError(t, st.Insert(Element{9}))
+		_, err = st.Root()
+		require.Error(t, err)
+		_, _, err = st.RootAndPath(0)
+		require.Error(t, err)
+		require.Error(t, st.Commit())
+		require.Equal(t, 4, st.IndexOf(Element{9}))
+
+		require.NoError(t, st.Update(4, Element{5}))
+		require.NoError(t, st.Commit())
+		root, err := st.Root()
+		require.NoError(t, err)
+		require.NotNil(t, root)
+	})
+}
@@ -0,0 +1,31 @@
+// Writes the zk-kit LeanIMT fixture read by Test_LeanMerkleTree.
+//
+//   cd testdata/lean_imt
+//   npm install --no-save @zk-kit/lean-imt poseidon-lite
+//   node generate.mjs
+//
+// export.json is the output of LeanIMT.export(), proofs.json holds generateProof(i) for every leaf with bigints as
+// decimal strings, versions.json the package versions that produced them.
+import { createRequire } from "node:module"
+import { writeFileSync } from "node:fs"
+import { LeanIMT } from "@zk-kit/lean-imt"
+import { poseidon2 } from "poseidon-lite"
+
+const require = createRequire(import.meta.url)
+const out = (name, data) => writeFileSync(new URL(name, import.meta.url), data + "\n")
+const json = (value) => JSON.stringify(value, (_, v) => (typeof v === "bigint" ? v.toString() : v), 2)
+
+// 5 leaves leave a node without a sibling on the first two levels
+const leaves = [1n, 2n, 3n, 4n, 5n]
+const tree = new LeanIMT((a, b) => poseidon2([a, b]))
+tree.insertMany(leaves)
+
+out("export.json", tree.export())
+out("proofs.json", json(leaves.map((_, i) => tree.generateProof(i))))
+out(
+    "versions.json",
+    json({
+        "@zk-kit/lean-imt": require("@zk-kit/lean-imt/package.json").version,
+        "poseidon-lite": require("poseidon-lite/package.json").version
+    })
+)
@@ -3,6 +3,7 @@ package fMerkleTree
 import (
 	"bytes"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 )
 
@@ -22,8 +23,40 @@ func (e Element) Cmp(x Element) bool {
 
 type HashFunction func(left Element, right Element) []byte
 
+// Hasher hashes two child nodes into their parent and reports failures instead of panicking
+type Hasher interface {
+	Hash(left Element, right Element) (Element, error)
+	Name() string
+	// OutputSize is the maximum length of a hash in bytes, 0 if unknown
+	OutputSize() int
+}
+
+// BatchHasher is implemented by hashers that can hash a whole layer at once
+type BatchHasher interface {
+	Hasher
+	// HashBatch hashes nodes[2i] with nodes[2i+1] for every i
+	HashBatch(nodes []Element) ([]Element, error)
+}
+
+// NaryHashFunction hashes the children of a node in trees of arity greater than 2
+type NaryHashFunction func(inputs []Element) []byte
+
+// NaryFromBinary adapts a binary hash function for trees of arity 2, it panics when called with any other number of
+// inputs and NewNaryMerkleTree rejects it for other arities
+func NaryFromBinary(hashFn HashFunction) NaryHashFunction {
+	return func(inputs []Element) []byte {
+		if len(inputs) != 2 {
+			panic(fmt.Sprintf("binary hash function called with %d inputs", len(inputs)))
+		}
+		return hashFn(inputs[0], inputs[1])
+	}
+}
+
 type ComparatorFunction func(left Element, right Element) bool
 
+// TreeOption configures optional behaviour of a tree at construction
+type TreeOption func(*BaseTree) error
+
 type SerializedTreeState interface {
 	GetLevels() int
 	GetRoot() Element
@@ -31,12 +64,45 @@ type SerializedTreeState interface {
 	GetZeros() ([]Element, error)
 }
 
+/**
+* Options and extra state of a serialized tree, implemented by the states returned by Serialize.
+* States that only implement SerializedTreeState are deserialized as trees without options.
+ */
+type SerializedTreeMetadata interface {
+	SerializedTreeState
+	GetRootHistorySize() int
+	GetRootHistory() ([]RootHistoryEntry, error)
+	GetTombstones() ([]int, error)
+	GetSortedPairs() bool
+	GetZeroElement() Element
+	GetDomain() string
+	GetLeaves() ([]Element, error)
+	GetFieldModulus() Element
+	GetHashName() string
+	GetHashFingerprint() Element
+	GetNodeWidth() int
+}
+
 type serializedTreeState struct {
 	Root   Element `db:"root"`
 	Levels int     `db:"levels"`
 	Layers []byte  `db:"layers"`
 	Zeros  []byte  `db:"zeros"`
 	ID     int     `db:"id"`
+
+	RootHistorySize int     `db:"root_history_size"`
+	RootHistory     []byte  `db:"root_history"`
+	Tombstones      []byte  `db:"tombstones"`
+	SortedPairs     bool    `db:"sorted_pairs"`
+	ZeroElement     Element `db:"zero_element"`
+	Domain          string  `db:"domain"`
+	Leaves          []byte  `db:"leaves"`
+	FieldModulus    Element `db:"field_modulus"`
+	HashName        string  `db:"hash_name"`
+	HashFingerprint Element `db:"hash_fingerprint"`
+	NodeWidth       int     `db:"node_width"`
+	// ShortNodes lists level, index and length of the fixed width nodes that are shorter than their slot
+	ShortNodes []byte `db:"short_nodes"`
 }
 
 func (st *serializedTreeState) GetRoot() Element {
@@ -49,7 +115,40 @@ func (st *serializedTreeState) GetLevels() int {
 
 func (st *serializedTreeState) GetLayers() ([][]Element, error) {
 	var out [][]Element
-	return out, GobDecode(st.Layers, &out)
+	if st.NodeWidth == 0 {
+		return out, GobDecode(st.Layers, &out)
+	}
+	// fixed width layers are stored as one buffer each, nodes are right-aligned in their slots
+	var storage [][]byte
+	if err := GobDecode(st.Layers, &storage); err != nil {
+		return nil, err
+	}
+	var short []int
+	if len(st.ShortNodes) > 0 {
+		if err := GobDecode(st.ShortNodes, &short); err != nil {
+			return nil, err
+		}
+	}
+	if len(short)%3 != 0 {
+		return nil, fmt.Errorf("invalid short nodes")
+	}
+	out = make([][]Element, len(storage))
+	for level, buf := range storage {
+		if len(buf)%st.NodeWidth != 0 {
+			return nil, fmt.Errorf("layer %d is not a multiple of %d bytes", level, st.NodeWidth)
+		}
+		for offset := 0; offset < len(buf); offset += st.NodeWidth {
+			out[level] = append(out[level], buf[offset:offset+st.NodeWidth:offset+st.NodeWidth])
+		}
+	}
+	for i := 0; i < len(short); i += 3 {
+		level, index, length := short[i], short[i+1], short[i+2]
+		if level < 0 || level >= len(out) || index < 0 || index >= len(out[level]) || length < 0 || length > st.NodeWidth {
+			return nil, fmt.Errorf("invalid short node at level %d index %d", level, index)
+		}
+		out[level][index] = out[level][index][st.NodeWidth-length:]
+	}
+	return out, nil
 }
 
 func (st *serializedTreeState) GetZeros() ([]Element, error) {
@@ -57,10 +156,128 @@ func (st *serializedTreeState) GetZeros() ([]Element, error) {
 	return out, GobDecode(st.Zeros, &out)
 }
 
+func (st *serializedTreeState) GetRootHistorySize() int {
+	return st.RootHistorySize
+}
+
+func (st *serializedTreeState) GetRootHistory() ([]RootHistoryEntry, error) {
+	var out []RootHistoryEntry
+	if len(st.RootHistory) == 0 {
+		return out, nil
+	}
+	return out, GobDecode(st.RootHistory, &out)
+}
+
+func (st *serializedTreeState) GetTombstones() ([]int, error) {
+	var out []int
+	if len(st.Tombstones) == 0 {
+		return out, nil
+	}
+	return out, GobDecode(st.Tombstones, &out)
+}
+
+func (st *serializedTreeState) GetSortedPairs() bool {
+	return st.SortedPairs
+}
+
+func (st *serializedTreeState) GetZeroElement() Element {
+	return st.ZeroElement
+}
+
+func (st *serializedTreeState) GetDomain() string {
+	return st.Domain
+}
+
+func (st *serializedTreeState) GetLeaves() ([]Element, error) {
+	var out []Element
+	if len(st.Leaves) == 0 {
+		return out, nil
+	}
+	return out, GobDecode(st.Leaves, &out)
+}
+
+func (st *serializedTreeState) GetFieldModulus() Element {
+	return st.FieldModulus
+}
+
+func (st *serializedTreeState) GetHashName() string {
+	return st.HashName
+}
+
+func (st *serializedTreeState) GetHashFingerprint() Element {
+	return st.HashFingerprint
+}
+
+func (st *serializedTreeState) GetNodeWidth() int {
+	return st.NodeWidth
+}
+
+// plainTreeState adds empty metadata to a state that does not carry any
+type plainTreeState struct {
+	SerializedTreeState
+}
+
+func (plainTreeState) GetRootHistorySize() int                     { return 0 }
+func (plainTreeState) GetRootHistory() ([]RootHistoryEntry, error) { return nil, nil }
+func (plainTreeState) GetTombstones() ([]int, error)               { return nil, nil }
+func (plainTreeState) GetSortedPairs() bool                        { return false }
+func (plainTreeState) GetZeroElement() Element                     { return nil }
+func (plainTreeState) GetDomain() string                           { return "" }
+func (plainTreeState) GetLeaves() ([]Element, error)               { return nil, nil }
+func (plainTreeState) GetFieldModulus() Element                    { return nil }
+func (plainTreeState) GetHashName() string                         { return "" }
+func (plainTreeState) GetHashFingerprint() Element                 { return nil }
+func (plainTreeState) GetNodeWidth() int                           { return 0 }
+
+func metadataOf(data SerializedTreeState) SerializedTreeMetadata {
+	if meta, ok := data.(SerializedTreeMetadata); ok {
+		return meta
+	}
+	return plainTreeState{data}
+}
+
 func NewSerializedTreeState(tree *MerkleTree) (SerializedTreeState, error) {
-	out := &serializedTreeState{Levels: tree.levels, Root: tree.Root()}
+	if err := tree.Commit(); err != nil {
+		return nil, err
+	}
+	out := &serializedTreeState{
+		Levels:          tree.levels,
+		Root:            tree.root(),
+		SortedPairs:     tree.sortedPairs,
+		ZeroElement:     tree.zeroElement,
+		HashName:        tree.hashName,
+		HashFingerprint: tree.fingerprint(),
+	}
 	var err error
-	out.Layers, err = GobEncode(tree.layers)
+	if tree.field != nil {
+		out.FieldModulus = tree.field.Bytes()
+	}
+	if tree.domain != nil {
+		out.Domain = tree.domain.Name
+		out.Leaves, err = GobEncode(tree.leaves)
+		if err != nil {
+			return out, err
+		}
+	}
+	if tree.width > 0 {
+		out.NodeWidth = tree.width
+		var short []int
+		for level, lengths := range tree.lengths {
+			for index, length := range lengths {
+				if int(length) != tree.width {
+					short = append(short, level, index, int(length))
+				}
+			}
+		}
+		if len(short) > 0 {
+			if out.ShortNodes, err = GobEncode(short); err != nil {
+				return out, err
+			}
+		}
+		out.Layers, err = GobEncode(tree.storage)
+	} else {
+		out.Layers, err = GobEncode(tree.layers)
+	}
 	if err != nil {
 		return out, err
 	}
@@ -68,6 +285,19 @@ func NewSerializedTreeState(tree *MerkleTree) (SerializedTreeState, error) {
 	if err != nil {
 		return out, err
 	}
+	if len(tree.tombstones) > 0 {
+		out.Tombstones, err = GobEncode(tree.Tombstones())
+		if err != nil {
+			return out, err
+		}
+	}
+	if tree.history != nil {
+		out.RootHistorySize = tree.RootHistorySize()
+		out.RootHistory, err = GobEncode(tree.RootHistory())
+		if err != nil {
+			return out, err
+		}
+	}
 	return out, nil
 }
 
@@ -89,3 +319,9 @@ type TreeSlice struct {
 	Edge     TreeEdge  `json:"edge"`
 	Elements []Element `json:"elements"`
 }
+
+type NaryProofPath struct {
+	PathElements [][]Element `json:"pathElements"`
+	PathIndices  []int       `json:"pathIndices"`
+	PathRoot     Element     `json:"pathRoot"`
+}